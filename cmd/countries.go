@@ -0,0 +1,47 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/kkkunny/TEW-hoi4/sdk"
+)
+
+var countriesCmd = &cobra.Command{
+	Use:   "countries",
+	Short: "国家相关",
+}
+
+var countriesRefreshCmd = &cobra.Command{
+	Use:     "refresh",
+	Short:   "按countries.json重新生成国家tag、颜色、名字和脚本文件",
+	Args:    cobra.NoArgs,
+	PreRunE: loadCountries,
+	RunE: func(_ *cobra.Command, _ []string) error {
+		return sdk.RefreshCountries(opts)
+	},
+}
+
+var countriesCheckCmd = &cobra.Command{
+	Use:     "check",
+	Short:   "检查countries.json中的tag、地区、子国家和upgrade_ratio",
+	Args:    cobra.NoArgs,
+	PreRunE: loadCountries,
+	RunE: func(_ *cobra.Command, _ []string) error {
+		return sdk.CheckCountries(opts)
+	},
+}
+
+var countriesTreeCmd = &cobra.Command{
+	Use:     "tree [tag...]",
+	Short:   "输出可变身国家的层级，不指定tag时输出所有顶层的可变身国家",
+	PreRunE: loadCountries,
+	RunE: func(_ *cobra.Command, args []string) error {
+		return sdk.PrintCountryTree(opts, args...)
+	},
+}
+
+func init() {
+	countriesRefreshCmd.Flags().Uint64Var(&opts.Seed, "seed", 0, "生成颜色使用的随机数种子")
+	countriesCmd.AddCommand(countriesRefreshCmd, countriesCheckCmd, countriesTreeCmd)
+	rootCmd.AddCommand(countriesCmd)
+}
@@ -0,0 +1,52 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/kkkunny/TEW-hoi4/sdk"
+)
+
+var flagsCmd = &cobra.Command{
+	Use:   "flags",
+	Short: "旗帜相关",
+}
+
+var flagSizes []string
+
+var flagsResizeCmd = &cobra.Command{
+	Use:   "resize",
+	Short: "按gfx/flags下的旗帜重新生成小旗帜和中旗帜",
+	Args:  cobra.NoArgs,
+	RunE: func(_ *cobra.Command, _ []string) error {
+		sizes := make([]sdk.FlagSize, len(flagSizes))
+		for i, name := range flagSizes {
+			switch name {
+			case sdk.SmallFlag.Dir:
+				sizes[i] = sdk.SmallFlag
+			case sdk.MediumFlag.Dir:
+				sizes[i] = sdk.MediumFlag
+			default:
+				return fmt.Errorf("unknown flag size `%s`", name)
+			}
+		}
+		return sdk.ResizeFlags(opts, sizes...)
+	},
+}
+
+var flagsCheckCmd = &cobra.Command{
+	Use:     "check",
+	Short:   "检查国家及其各类型外观tag的旗帜是否齐全",
+	Args:    cobra.NoArgs,
+	PreRunE: loadCountries,
+	RunE: func(_ *cobra.Command, _ []string) error {
+		return sdk.CheckFlags(opts)
+	},
+}
+
+func init() {
+	flagsResizeCmd.Flags().StringSliceVar(&flagSizes, "size", []string{sdk.SmallFlag.Dir, sdk.MediumFlag.Dir}, "要生成的尺寸（small、medium）")
+	flagsCmd.AddCommand(flagsResizeCmd, flagsCheckCmd)
+	rootCmd.AddCommand(flagsCmd)
+}
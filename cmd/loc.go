@@ -0,0 +1,27 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/kkkunny/TEW-hoi4/sdk"
+)
+
+var locCmd = &cobra.Command{
+	Use:   "loc",
+	Short: "本地化相关",
+}
+
+var locCheckCmd = &cobra.Command{
+	Use:     "check",
+	Short:   "检查本地化文件的BOM、文件头、重复键以及国家名字是否齐全",
+	Args:    cobra.NoArgs,
+	PreRunE: loadCountries,
+	RunE: func(_ *cobra.Command, _ []string) error {
+		return sdk.CheckLocalisation(opts)
+	},
+}
+
+func init() {
+	locCmd.AddCommand(locCheckCmd)
+	rootCmd.AddCommand(locCmd)
+}
@@ -0,0 +1,51 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/kkkunny/TEW-hoi4/sdk"
+)
+
+var mapCmd = &cobra.Command{
+	Use:   "map",
+	Short: "地图相关",
+}
+
+var mapCheckCmd = &cobra.Command{
+	Use:   "check",
+	Short: "检查省份定义、额外相邻关系以及省份与地区、战略区域的从属关系",
+	Args:  cobra.NoArgs,
+	RunE: func(_ *cobra.Command, _ []string) error {
+		return sdk.CheckMap(opts)
+	},
+}
+
+var (
+	mapRenderMode   string
+	mapRenderTag    string
+	mapRenderOutput string
+)
+
+var mapRenderCmd = &cobra.Command{
+	Use:   "render",
+	Short: "将政治地图、某个国家的核心与宣称地图或大洲地图渲染为png",
+	Args:  cobra.NoArgs,
+	RunE: func(_ *cobra.Command, _ []string) error {
+		return sdk.RenderMap(opts, sdk.MapMode(mapRenderMode), strings.ToUpper(mapRenderTag), mapRenderOutput)
+	},
+}
+
+func init() {
+	modes := make([]string, len(sdk.MapModes))
+	for i, mode := range sdk.MapModes {
+		modes[i] = string(mode)
+	}
+	mapRenderCmd.Flags().StringVar(&mapRenderMode, "mode", string(sdk.PoliticalMap), fmt.Sprintf("渲染模式（%s）", strings.Join(modes, "、")))
+	mapRenderCmd.Flags().StringVar(&mapRenderTag, "tag", "", "cores模式下的国家tag")
+	mapRenderCmd.Flags().StringVarP(&mapRenderOutput, "output", "o", "", "输出的png路径，默认为当前目录下的<模式>.png")
+	mapCmd.AddCommand(mapCheckCmd, mapRenderCmd)
+	rootCmd.AddCommand(mapCmd)
+}
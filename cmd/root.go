@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/kkkunny/TEW-hoi4/config"
+	"github.com/kkkunny/TEW-hoi4/sdk"
+)
+
+var (
+	opts           sdk.Options
+	pathConfigFile string
+)
+
+var rootCmd = &cobra.Command{
+	Use:           "tew",
+	Short:         "TheEmptyWorld mod工具",
+	SilenceUsage:  true,
+	SilenceErrors: true,
+	PersistentPreRunE: func(cmd *cobra.Command, _ []string) error {
+		opts.Out = cmd.OutOrStdout()
+		if opts.Diff {
+			opts.DryRun = true
+		}
+
+		err := config.LoadPaths(pathConfigFile, config.PathConfig{Mod: opts.ModPath, Game: opts.GamePath})
+		if err != nil {
+			return err
+		}
+		opts.ModPath, opts.GamePath = config.TEWRootPath, config.HOI4RootPath
+		if opts.Verbose {
+			fmt.Fprintf(opts.Out, "mod路径: %s\n游戏路径: %s\n", opts.ModPath, opts.GamePath)
+		}
+		return config.CheckModPath()
+	},
+}
+
+// loadCountries 读取countries.json，只用作需要国家列表的命令的PreRunE，使countries.json中的错误不影响其他命令
+func loadCountries(_ *cobra.Command, _ []string) error {
+	_, err := config.LoadCountries(config.CountriesPath)
+	return err
+}
+
+func init() {
+	rootCmd.CompletionOptions.DisableDefaultCmd = true
+
+	flags := rootCmd.PersistentFlags()
+	flags.StringVar(&pathConfigFile, "config", "", fmt.Sprintf("路径配置文件（默认$%s或./%s）", config.EnvPathConfig, config.DefaultPathConfigFile))
+	flags.StringVar(&opts.ModPath, "mod", "", fmt.Sprintf("mod根目录，覆盖$%s", config.EnvTEWRootPath))
+	flags.StringVar(&opts.GamePath, "game", "", fmt.Sprintf("游戏根目录，覆盖$%s", config.EnvHOI4RootPath))
+	flags.BoolVar(&opts.DryRun, "dry-run", false, "只输出将要新增和修改的文件，不写入文件")
+	flags.BoolVar(&opts.Diff, "diff", false, "输出与磁盘上文件的差异，不写入文件（隐含--dry-run）")
+	flags.BoolVarP(&opts.Verbose, "verbose", "v", false, "输出详细信息")
+}
+
+// Execute 执行命令行，返回进程退出码
+func Execute() int {
+	if err := rootCmd.Execute(); err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,27 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/kkkunny/TEW-hoi4/sdk"
+)
+
+var statesCmd = &cobra.Command{
+	Use:   "states",
+	Short: "地区相关",
+}
+
+var statesEditCmd = &cobra.Command{
+	Use:     "edit rules.yaml",
+	Aliases: []string{"apply"},
+	Short:   "按规则文件批量修改history/states",
+	Args:    cobra.ExactArgs(1),
+	RunE: func(_ *cobra.Command, args []string) error {
+		return sdk.ApplyStateRules(opts, args[0])
+	},
+}
+
+func init() {
+	statesCmd.AddCommand(statesEditCmd)
+	rootCmd.AddCommand(statesCmd)
+}
@@ -1,11 +1,20 @@
 package config
 
 import (
+	"bytes"
 	_ "embed"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
 
 	"github.com/kkkunny/stl/container/optional"
 	stlslices "github.com/kkkunny/stl/container/slices"
+
+	"github.com/kkkunny/TEW-hoi4/util"
 )
 
 type Country struct {
@@ -15,17 +24,341 @@ type Country struct {
 	Color        optional.Optional[[3]uint8] `json:"color,omitempty"`
 	Sons         optional.Optional[[]string] `json:"sons,omitempty"`
 	UpgradeRatio optional.Optional[int64]    `json:"upgrade_ratio,omitempty"`
+	// Types 以国家类型id为键，覆盖该类型外观tag的名字、颜色和旗帜
+	Types map[string]*CountryTypeOverride `json:"types,omitempty"`
+
+	// keys countries.json中字段的顺序，写回时保持不变
+	keys []string
+	// line、keyLines 国家对象及其各字段在countries.json中的行号
+	line     int
+	keyLines map[string]int
+}
+
+// Position 文件中的位置，如countries.json中的字段、本地化文件中的键，Line为0时只有文件
+type Position struct {
+	Path string
+	Line int
+}
+
+func (p Position) String() string {
+	if p.Line == 0 {
+		return p.Path
+	}
+	return fmt.Sprintf("%s:%d", p.Path, p.Line)
+}
+
+// Pos 返回字段key在countries.json中的位置，key为空或不存在时返回国家对象的位置
+func (c *Country) Pos(key string) Position {
+	if line, ok := c.keyLines[key]; ok {
+		return Position{Path: countriesSource, Line: line}
+	}
+	return Position{Path: countriesSource, Line: c.line}
+}
+
+// TypeOverride 返回国家在类型下的外观tag设置，未设置时返回空设置
+func (c *Country) TypeOverride(typeID string) *CountryTypeOverride {
+	if override, ok := c.Types[typeID]; ok && override != nil {
+		return override
+	}
+	return &CountryTypeOverride{}
+}
+
+// CountryTypeOverride 国家在某个类型下的外观tag设置，未设置的字段按国家类型生成
+type CountryTypeOverride struct {
+	// Name 名字
+	Name string `json:"name,omitempty"`
+	// DEF 带定冠词的名字，未设置时与Name相同
+	DEF string `json:"def,omitempty"`
+	// Color 颜色，未设置时由国家颜色与类型颜色混合得到
+	Color optional.Optional[[3]uint8] `json:"color,omitempty"`
+	// Flag 旗帜文件，相对于gfx/flags
+	Flag string `json:"flag,omitempty"`
+}
+
+// countriesFile countries.json相对于仓库根目录的路径
+var countriesFile = filepath.Join("config", "countries.json")
+
+// CountriesPath countries.json的路径，存在时优先于编译时嵌入的数据，生成的数据也写回该文件
+// 为空时表示没有找到仓库中的countries.json，只使用嵌入的数据
+var CountriesPath = defaultCountriesPath("")
+
+// defaultCountriesPath 依次在dir（路径配置文件所在目录，可为空）、可执行文件所在目录和当前目录下查找config/countries.json，都不存在时返回空
+func defaultCountriesPath(dir string) string {
+	dirs := []string{dir}
+	if exe, err := os.Executable(); err == nil {
+		if resolved, err := filepath.EvalSymlinks(exe); err == nil {
+			exe = resolved
+		}
+		dirs = append(dirs, filepath.Dir(exe))
+	}
+	dirs = append(dirs, ".")
+	for _, dir := range dirs {
+		if dir == "" {
+			continue
+		}
+		path := filepath.Join(dir, countriesFile)
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+	return ""
 }
 
 //go:embed countries.json
 var countriesData []byte
-var Countries = func() map[string]*Country {
+
+// countriesSource 当前国家列表的来源文件，用于输出错误位置
+var countriesSource = "countries.json"
+
+// CountriesSource 返回当前国家列表的来源文件，使用嵌入的数据时为countries.json
+func CountriesSource() string {
+	return countriesSource
+}
+
+// CountryList、Countries 由LoadCountries读取
+var (
+	// CountryList 按countries.json中的顺序排列的国家
+	CountryList []*Country
+	// Countries 以tag为键的国家
+	Countries map[string]*Country
+)
+
+// LoadCountries 读取国家列表并设置CountryList和Countries，path为空或对应的文件不存在时使用编译时嵌入的数据
+func LoadCountries(path string) ([]*Country, error) {
+	_, err := LoadCountryTypes()
+	if err != nil {
+		return nil, err
+	}
+	source, data := "countries.json", countriesData
+	if path != "" {
+		fileData, err := os.ReadFile(path)
+		if err == nil {
+			source, data = path, fileData
+		} else if !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+	}
+	err = setCountries(source, data)
+	if err != nil {
+		return nil, err
+	}
+	return CountryList, nil
+}
+
+func setCountries(source string, data []byte) error {
 	var countries []*Country
-	err := json.Unmarshal(countriesData, &countries)
+	err := json.Unmarshal(data, &countries)
+	if err != nil {
+		return jsonError(source, data, err)
+	}
+	layouts, err := objectLayouts(data)
 	if err != nil {
-		panic(err)
+		return jsonError(source, data, err)
+	}
+	for i, c := range countries {
+		c.keys, c.line, c.keyLines = layouts[i].keys, layouts[i].line, layouts[i].keyLines
+		for id := range c.Types {
+			if !slices.ContainsFunc(CountryTypes, func(t *CountryType) bool { return t.ID == id }) {
+				return fmt.Errorf("%s: country `%s`: unknown country type `%s`", Position{Path: source, Line: c.keyLines["types"]}, c.ID, id)
+			}
+		}
 	}
-	return stlslices.ToMap(countries, func(c *Country) (string, *Country) {
+	countriesSource = source
+	CountryList = countries
+	Countries = stlslices.ToMap(countries, func(c *Country) (string, *Country) {
 		return c.ID, c
 	})
-}()
+	return nil
+}
+
+// SaveCountries 将国家列表按countries.json的格式写入文件
+func SaveCountries(path string) error {
+	data, err := EncodeCountries(CountryList)
+	if err != nil {
+		return err
+	}
+	_, err = util.WriteFileAtomic(path, data, 0666)
+	return err
+}
+
+// EncodeCountries 按countries.json的格式编码，每个字段一行，数组写在同一行
+func EncodeCountries(countries []*Country) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString("[\n")
+	for i, c := range countries {
+		values := map[string]any{
+			"id":     c.ID,
+			"name":   c.Name,
+			"region": c.Region,
+		}
+		if c.Color.IsSome() {
+			values["color"] = c.Color.MustValue()
+		}
+		if c.Sons.IsSome() {
+			values["sons"] = c.Sons.MustValue()
+		}
+		if c.UpgradeRatio.IsSome() {
+			values["upgrade_ratio"] = c.UpgradeRatio.MustValue()
+		}
+		if len(c.Types) != 0 {
+			values["types"] = c.Types
+		}
+		var fields []string
+		for _, key := range append(slices.Clone(c.keys), countryKeys...) {
+			if _, ok := values[key]; ok && !slices.Contains(fields, key) {
+				fields = append(fields, key)
+			}
+		}
+
+		buf.WriteString("  {\n")
+		for j, key := range fields {
+			value, err := encodeCountryField(values[key])
+			if err != nil {
+				return nil, err
+			}
+			fmt.Fprintf(&buf, "    %q: %s", key, value)
+			if j != len(fields)-1 {
+				buf.WriteByte(',')
+			}
+			buf.WriteByte('\n')
+		}
+		buf.WriteString("  }")
+		if i != len(countries)-1 {
+			buf.WriteByte(',')
+		}
+		buf.WriteByte('\n')
+	}
+	buf.WriteString("]")
+	return buf.Bytes(), nil
+}
+
+// countryKeys Country的字段顺序，用于新增的国家或字段
+var countryKeys = []string{"id", "name", "region", "color", "sons", "upgrade_ratio", "types"}
+
+// objectLayout json对象的字段顺序与行号
+type objectLayout struct {
+	keys     []string
+	line     int
+	keyLines map[string]int
+}
+
+// objectLayouts 返回json数组中每个对象的字段顺序与行号
+func objectLayouts(data []byte) ([]objectLayout, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	if _, err := decoder.Token(); err != nil {
+		return nil, err
+	}
+	var layouts []objectLayout
+	for decoder.More() {
+		if _, err := decoder.Token(); err != nil {
+			return nil, err
+		}
+		layout := objectLayout{
+			line:     lineAt(data, decoder.InputOffset()),
+			keyLines: make(map[string]int),
+		}
+		for decoder.More() {
+			key, err := decoder.Token()
+			if err != nil {
+				return nil, err
+			}
+			layout.keys = append(layout.keys, key.(string))
+			layout.keyLines[key.(string)] = lineAt(data, decoder.InputOffset())
+			var value json.RawMessage
+			if err = decoder.Decode(&value); err != nil {
+				return nil, err
+			}
+		}
+		if _, err := decoder.Token(); err != nil {
+			return nil, err
+		}
+		layouts = append(layouts, layout)
+	}
+	return layouts, nil
+}
+
+// lineAt 返回偏移量所在的行号，从1开始
+func lineAt(data []byte, offset int64) int {
+	offset = min(max(offset, 0), int64(len(data)))
+	return bytes.Count(data[:offset], []byte("\n")) + 1
+}
+
+// jsonError 为json错误加上出错的位置
+func jsonError(source string, data []byte, err error) error {
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &syntaxErr):
+		return fmt.Errorf("%s: %s", Position{Path: source, Line: lineAt(data, syntaxErr.Offset)}, err.Error())
+	case errors.As(err, &typeErr):
+		return fmt.Errorf("%s: %s", Position{Path: source, Line: lineAt(data, typeErr.Offset)}, err.Error())
+	default:
+		return fmt.Errorf("%s: %s", source, err.Error())
+	}
+}
+
+func encodeCountryField(v any) (string, error) {
+	var items []any
+	switch v := v.(type) {
+	case optional.Optional[[3]uint8]:
+		return encodeCountryField(v.MustValue())
+	case [3]uint8:
+		items = []any{v[0], v[1], v[2]}
+	case []string:
+		items = stlslices.Map(v, func(_ int, s string) any { return s })
+	case map[string]*CountryTypeOverride:
+		return encodeCountryTypes(v)
+	default:
+		data, err := json.Marshal(v)
+		return string(data), err
+	}
+	strs := make([]string, len(items))
+	for i, item := range items {
+		data, err := json.Marshal(item)
+		if err != nil {
+			return "", err
+		}
+		strs[i] = string(data)
+	}
+	return "[" + strings.Join(strs, ", ") + "]", nil
+}
+
+// encodeCountryTypes 按国家类型的顺序编码，每个类型一行
+func encodeCountryTypes(types map[string]*CountryTypeOverride) (string, error) {
+	var buf strings.Builder
+	buf.WriteString("{\n")
+	var i int
+	for _, t := range CountryTypes {
+		override, ok := types[t.ID]
+		if !ok {
+			continue
+		}
+		var fields []string
+		for _, field := range []struct {
+			key   string
+			value any
+			empty bool
+		}{
+			{"name", override.Name, override.Name == ""},
+			{"def", override.DEF, override.DEF == ""},
+			{"color", override.Color, override.Color.IsNone()},
+			{"flag", override.Flag, override.Flag == ""},
+		} {
+			if field.empty {
+				continue
+			}
+			value, err := encodeCountryField(field.value)
+			if err != nil {
+				return "", err
+			}
+			fields = append(fields, fmt.Sprintf("%q: %s", field.key, value))
+		}
+		fmt.Fprintf(&buf, "      %q: {%s}", t.ID, strings.Join(fields, ", "))
+		if i++; i != len(types) {
+			buf.WriteByte(',')
+		}
+		buf.WriteByte('\n')
+	}
+	buf.WriteString("    }")
+	return buf.String(), nil
+}
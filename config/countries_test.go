@@ -0,0 +1,41 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadCountries(t *testing.T) {
+	list, countries, source := CountryList, Countries, countriesSource
+	t.Cleanup(func() {
+		CountryList, Countries, countriesSource = list, countries, source
+	})
+
+	dir := t.TempDir()
+	var embedded []*Country
+	for _, path := range []string{filepath.Join(dir, "missing.json"), ""} {
+		var err error
+		embedded, err = LoadCountries(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(embedded) == 0 || len(Countries) != len(embedded) || embedded[0].Pos("id").Path != "countries.json" {
+			t.Fatalf("expect the embedded countries for %q, got %d", path, len(embedded))
+		}
+	}
+
+	fp := filepath.Join(dir, "countries.json")
+	err := os.WriteFile(fp, []byte("[\n  {\n    \"id\": \"FRA\",\n    \"name\": 1\n  }\n]"), 0666)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = LoadCountries(fp)
+	if err == nil || !strings.HasPrefix(err.Error(), fp+":4: ") {
+		t.Fatalf("expect error at line 4, got %v", err)
+	}
+	if len(CountryList) != len(embedded) {
+		t.Fatal("expect the countries to be kept after a failed load")
+	}
+}
@@ -0,0 +1,106 @@
+package config
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// CountryType 国家类型（政体），决定外观tag的颜色、名字以及判断条件，格式见country_types.schema.json
+type CountryType struct {
+	ID         string   `json:"id"`
+	Color      [3]uint8 `json:"color"`
+	NameFormat string   `json:"name_format"`
+	Ideas      []string `json:"ideas"`
+}
+
+// CosmeticTag 国家在该类型下的外观tag
+func (t *CountryType) CosmeticTag(country string) string {
+	return fmt.Sprintf("%s_type_%s", country, t.ID)
+}
+
+// Flag 属于该类型的国家拥有的国家flag
+func (t *CountryType) Flag() string {
+	return "country_type_" + t.ID
+}
+
+//go:embed country_types.json
+var countryTypesData []byte
+
+// CountryTypes 按判断顺序排列的国家类型，由LoadCountryTypes读取
+var CountryTypes []*CountryType
+
+var parseEmbeddedCountryTypes = sync.OnceValues(func() ([]*CountryType, error) {
+	types, err := ParseCountryTypes(countryTypesData)
+	if err != nil {
+		return nil, fmt.Errorf("country_types.json: %w", err)
+	}
+	return types, nil
+})
+
+// LoadCountryTypes 读取编译时嵌入的国家类型并设置CountryTypes，只在第一次调用时解析
+func LoadCountryTypes() ([]*CountryType, error) {
+	types, err := parseEmbeddedCountryTypes()
+	if err != nil {
+		return nil, err
+	}
+	CountryTypes = types
+	return types, nil
+}
+
+var (
+	countryTypeIDRegexp   = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+	countryTypeIdeaRegexp = regexp.MustCompile(`^gov_[a-z0-9_]+$`)
+)
+
+// ParseCountryTypes 解析并校验国家类型
+func ParseCountryTypes(data []byte) ([]*CountryType, error) {
+	var file struct {
+		Schema string         `json:"$schema"`
+		Types  []*CountryType `json:"types"`
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	err := decoder.Decode(&file)
+	if err != nil {
+		return nil, err
+	}
+	if len(file.Types) == 0 {
+		return nil, errors.New("no country type")
+	}
+
+	var errs []error
+	ids := make(map[string]struct{}, len(file.Types))
+	ideas := make(map[string]string)
+	for i, t := range file.Types {
+		if !countryTypeIDRegexp.MatchString(t.ID) {
+			errs = append(errs, fmt.Errorf("types[%d]: invalid id `%s`", i, t.ID))
+		} else if _, ok := ids[t.ID]; ok {
+			errs = append(errs, fmt.Errorf("types[%d]: duplicate id `%s`", i, t.ID))
+		}
+		ids[t.ID] = struct{}{}
+		if strings.Count(t.NameFormat, "%") != 1 || !strings.Contains(t.NameFormat, "%s") {
+			errs = append(errs, fmt.Errorf("types[%d]: name_format `%s` must contain exactly one %%s", i, t.NameFormat))
+		}
+		if len(t.Ideas) == 0 {
+			errs = append(errs, fmt.Errorf("types[%d]: `%s` has no ideas", i, t.ID))
+		}
+		for _, idea := range t.Ideas {
+			if !countryTypeIdeaRegexp.MatchString(idea) {
+				errs = append(errs, fmt.Errorf("types[%d]: invalid idea `%s`", i, idea))
+			} else if other, ok := ideas[idea]; ok {
+				errs = append(errs, fmt.Errorf("types[%d]: idea `%s` already belongs to `%s`", i, idea, other))
+			}
+			ideas[idea] = t.ID
+		}
+	}
+	if len(errs) != 0 {
+		return nil, errors.Join(errs...)
+	}
+	return file.Types, nil
+}
@@ -0,0 +1,86 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseCountryTypes(t *testing.T) {
+	types, err := ParseCountryTypes(countryTypesData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(types) != 7 || types[0].ID != "anarchism" {
+		t.Fatalf("unexpected embedded country types %+v", types)
+	}
+
+	types, err = ParseCountryTypes([]byte(`{"$schema": "x", "types": [{"id": "theocracy", "color": [1, 2, 3], "name_format": "%s神权国", "ideas": ["gov_theocracy"]}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(types) != 1 || types[0].Color != [3]uint8{1, 2, 3} || types[0].CosmeticTag("FRA") != "FRA_type_theocracy" {
+		t.Fatalf("unexpected country types %+v", types)
+	}
+}
+
+func TestParseCountryTypesError(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		err  string
+	}{
+		{name: "unknown field", data: `{"types": [{"id": "a", "colour": [1, 2, 3]}]}`, err: "unknown field \"colour\""},
+		{name: "wrong type", data: `{"types": [{"id": "a", "color": "red"}]}`, err: "cannot unmarshal"},
+		{name: "empty", data: `{"types": []}`, err: "no country type"},
+		{name: "invalid id", data: `{"types": [{"id": "Bad", "name_format": "%s", "ideas": ["gov_a"]}]}`, err: "invalid id `Bad`"},
+		{name: "duplicate id", data: `{"types": [{"id": "a", "name_format": "%s", "ideas": ["gov_a"]}, {"id": "a", "name_format": "%s", "ideas": ["gov_b"]}]}`, err: "duplicate id `a`"},
+		{name: "name format", data: `{"types": [{"id": "a", "name_format": "%s%d", "ideas": ["gov_a"]}]}`, err: "must contain exactly one %s"},
+		{name: "no ideas", data: `{"types": [{"id": "a", "name_format": "%s"}]}`, err: "`a` has no ideas"},
+		{name: "invalid idea", data: `{"types": [{"id": "a", "name_format": "%s", "ideas": ["anarchy"]}]}`, err: "invalid idea `anarchy`"},
+		{name: "shared idea", data: `{"types": [{"id": "a", "name_format": "%s", "ideas": ["gov_a"]}, {"id": "b", "name_format": "%s", "ideas": ["gov_a"]}]}`, err: "idea `gov_a` already belongs to `a`"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseCountryTypes([]byte(tt.data))
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Fatalf("expect error containing %q, got %v", tt.err, err)
+			}
+		})
+	}
+}
+
+func TestCountryTypeOverride(t *testing.T) {
+	if _, err := LoadCountryTypes(); err != nil {
+		t.Fatal(err)
+	}
+	list, countries, source := CountryList, Countries, countriesSource
+	t.Cleanup(func() {
+		CountryList, Countries, countriesSource = list, countries, source
+	})
+
+	err := setCountries("test.json", []byte(`[
+  {
+    "id": "FRA",
+    "name": "法国",
+    "region": "European",
+    "types": {
+      "feudalism": {"name": "法兰西王国", "color": [1, 2, 3]}
+    }
+  }
+]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	override := Countries["FRA"].TypeOverride("feudalism")
+	if override.Name != "法兰西王国" || override.DEF != "" || override.Color.MustValue() != [3]uint8{1, 2, 3} {
+		t.Fatalf("unexpected override %+v", override)
+	}
+	if override = Countries["FRA"].TypeOverride("fascism"); override.Name != "" || override.Color.IsSome() {
+		t.Fatalf("expect empty override, got %+v", override)
+	}
+
+	err = setCountries("test.json", []byte("[\n  {\n    \"id\": \"FRA\",\n    \"types\": {\"theocracy\": {\"name\": \"x\"}}\n  }\n]"))
+	if err == nil || err.Error() != "test.json:4: country `FRA`: unknown country type `theocracy`" {
+		t.Fatalf("unexpected error %v", err)
+	}
+}
@@ -0,0 +1,100 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+)
+
+const (
+	hoi4GameDir     = "Hearts of Iron IV"
+	paradoxDocument = "Paradox Interactive"
+)
+
+// DetectGamePath 在常见的Steam库中查找游戏根目录
+func DetectGamePath() (string, bool) {
+	for _, steam := range steamRoots() {
+		for _, lib := range steamLibraries(steam) {
+			p := filepath.Join(lib, "steamapps", "common", hoi4GameDir)
+			if isDir(p) {
+				return p, true
+			}
+		}
+	}
+	return "", false
+}
+
+// DetectHOI4ModPath 在常见的Paradox文档目录中查找mod目录
+func DetectHOI4ModPath() (string, bool) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", false
+	}
+	var candidates []string
+	switch runtime.GOOS {
+	case "linux":
+		dataHome := os.Getenv("XDG_DATA_HOME")
+		if dataHome == "" {
+			dataHome = filepath.Join(home, ".local", "share")
+		}
+		candidates = append(candidates, filepath.Join(dataHome, paradoxDocument, hoi4GameDir, "mod"))
+	case "darwin", "windows":
+		candidates = append(candidates, filepath.Join(home, "Documents", paradoxDocument, hoi4GameDir, "mod"))
+	}
+	for _, p := range candidates {
+		if isDir(p) {
+			return p, true
+		}
+	}
+	return "", false
+}
+
+// steamRoots 常见的Steam安装目录
+func steamRoots() []string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil
+	}
+	switch runtime.GOOS {
+	case "linux":
+		return []string{
+			filepath.Join(home, ".steam", "steam"),
+			filepath.Join(home, ".local", "share", "Steam"),
+			filepath.Join(home, ".var", "app", "com.valvesoftware.Steam", ".local", "share", "Steam"),
+			filepath.Join(home, "snap", "steam", "common", ".local", "share", "Steam"),
+		}
+	case "darwin":
+		return []string{filepath.Join(home, "Library", "Application Support", "Steam")}
+	case "windows":
+		return []string{
+			filepath.Join(os.Getenv("ProgramFiles(x86)"), "Steam"),
+			filepath.Join(os.Getenv("ProgramFiles"), "Steam"),
+		}
+	default:
+		return nil
+	}
+}
+
+var (
+	steamLibraryPathRegexp = regexp.MustCompile(`"path"\s+"((?:[^"\\]|\\.)*)"`)
+	vdfEscapeRegexp        = regexp.MustCompile(`\\(.)`)
+)
+
+// steamLibraries 返回Steam安装目录本身以及libraryfolders.vdf中登记的其它库
+func steamLibraries(steam string) []string {
+	libs := []string{steam}
+	data, err := os.ReadFile(filepath.Join(steam, "steamapps", "libraryfolders.vdf"))
+	if err != nil {
+		return libs
+	}
+	for _, match := range steamLibraryPathRegexp.FindAllStringSubmatch(string(data), -1) {
+		libs = append(libs, vdfEscapeRegexp.ReplaceAllString(match[1], "$1"))
+	}
+	return libs
+}
+
+func isDir(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
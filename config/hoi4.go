@@ -1,10 +1,175 @@
 package config
 
-import "path/filepath"
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultPathConfigFile 默认的路径配置文件，位于当前目录
+const DefaultPathConfigFile = "tew.yaml"
+
+// 路径相关的环境变量
+const (
+	EnvPathConfig    = "TEW_CONFIG"
+	EnvHOI4RootPath  = "TEW_GAME_PATH"
+	EnvHOI4ModPath   = "TEW_HOI4_MOD_PATH"
+	EnvHOI4MyModPath = "TEW_MY_MOD_PATH"
+	EnvTEWRootPath   = "TEW_MOD_PATH"
+	EnvCountriesPath = "TEW_COUNTRIES_PATH"
+	EnvStatePath     = "TEW_STATE_PATH"
+	EnvTemplatePath  = "TEW_TEMPLATE_PATH"
+)
+
+// 各路径的默认值
+const (
+	defaultHOI4MyModPath = "mod"
+	tewModDir            = "TheEmptyWorld"
+)
 
 var (
-	HOI4RootPath  = `F:\SteamLibrary\steamapps\common\Hearts of Iron IV`
-	HOI4ModPath   = `C:\Users\14012\Documents\Paradox Interactive\Hearts of Iron IV\mod`
-	HOI4MyModPath = `W:\code\go\github.com\kkkunny\TEW-hoi4\mod`
-	TEWRootPath   = filepath.Join(HOI4MyModPath, "TheEmptyWorld")
+	// HOI4RootPath 游戏根目录
+	HOI4RootPath string
+	// HOI4ModPath 游戏文档中的mod目录
+	HOI4ModPath string
+	// HOI4MyModPath 本仓库的mod目录
+	HOI4MyModPath = defaultHOI4MyModPath
+	// TEWRootPath TheEmptyWorld mod根目录
+	TEWRootPath = filepath.Join(HOI4MyModPath, tewModDir)
+	// StatePath 保存工具自身状态（如生成文件的清单）的目录，应位于mod之外，默认为用户配置目录下的tew
+	StatePath = defaultStatePath()
+	// TemplatePath 覆盖内置生成模板的目录，其中与内置模板同名的文件优先使用，优先级低于mod中的tew_templates
+	TemplatePath string
 )
+
+func defaultStatePath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "tew")
+}
+
+// PathConfig 路径配置文件，相对路径相对于配置文件所在目录
+// 也用于表示环境变量和命令行参数中的路径，空字符串表示未设置
+type PathConfig struct {
+	Game      string `yaml:"game"`
+	HOI4Mod   string `yaml:"hoi4_mod"`
+	MyMod     string `yaml:"my_mod"`
+	Mod       string `yaml:"mod"`
+	Countries string `yaml:"countries"`
+	State     string `yaml:"state"`
+	Templates string `yaml:"templates"`
+}
+
+// LoadPaths 确定各路径，优先级从低到高依次为：自动探测、配置文件、环境变量、命令行参数flags
+// path为空时使用环境变量TEW_CONFIG指定的文件，都未指定时读取当前目录下的tew.yaml（不存在则跳过）
+// mod根目录未设置时为my_mod下的TheEmptyWorld，高优先级中设置的my_mod覆盖低优先级中的mod
+func LoadPaths(path string, flags PathConfig) error {
+	var detected PathConfig
+	detected.Game, _ = DetectGamePath()
+	detected.HOI4Mod, _ = DetectHOI4ModPath()
+
+	var file PathConfig
+	var fileDir string
+	if path == "" {
+		path = os.Getenv(EnvPathConfig)
+	}
+	explicit := path != ""
+	if !explicit {
+		path = DefaultPathConfigFile
+	}
+	data, err := os.ReadFile(path)
+	if err != nil && (explicit || !errors.Is(err, os.ErrNotExist)) {
+		return fmt.Errorf("read path config `%s` error: %s", path, err.Error())
+	} else if err == nil {
+		err = yaml.Unmarshal(data, &file)
+		if err != nil {
+			return fmt.Errorf("`%s` parse error: %s", path, err.Error())
+		}
+		fileDir = filepath.Dir(path)
+		file.resolve(fileDir)
+	}
+
+	env := PathConfig{
+		Game:      os.Getenv(EnvHOI4RootPath),
+		HOI4Mod:   os.Getenv(EnvHOI4ModPath),
+		MyMod:     os.Getenv(EnvHOI4MyModPath),
+		Mod:       os.Getenv(EnvTEWRootPath),
+		Countries: os.Getenv(EnvCountriesPath),
+		State:     os.Getenv(EnvStatePath),
+		Templates: os.Getenv(EnvTemplatePath),
+	}
+
+	// 所有层合并之后再确定mod根目录
+	merged := PathConfig{MyMod: defaultHOI4MyModPath, Countries: defaultCountriesPath(fileDir), State: defaultStatePath()}
+	mod := filepath.Join(defaultHOI4MyModPath, tewModDir)
+	for _, layer := range []PathConfig{detected, file, env, flags} {
+		setPath(&merged.Game, layer.Game)
+		setPath(&merged.HOI4Mod, layer.HOI4Mod)
+		setPath(&merged.Countries, layer.Countries)
+		setPath(&merged.State, layer.State)
+		setPath(&merged.Templates, layer.Templates)
+		setPath(&merged.MyMod, layer.MyMod)
+		if !setPath(&mod, layer.Mod) && layer.MyMod != "" {
+			mod = filepath.Join(layer.MyMod, tewModDir)
+		}
+	}
+	HOI4RootPath, HOI4ModPath, HOI4MyModPath = merged.Game, merged.HOI4Mod, merged.MyMod
+	TEWRootPath, CountriesPath, StatePath, TemplatePath = mod, merged.Countries, merged.State, merged.Templates
+	return nil
+}
+
+// resolve 展开以~开头的路径，并将相对路径转换为相对于dir的路径
+func (cfg *PathConfig) resolve(dir string) {
+	home, _ := os.UserHomeDir()
+	for _, p := range []*string{&cfg.Game, &cfg.HOI4Mod, &cfg.MyMod, &cfg.Mod, &cfg.Countries, &cfg.State, &cfg.Templates} {
+		if home != "" && (*p == "~" || strings.HasPrefix(*p, "~/")) {
+			*p = filepath.Join(home, (*p)[1:])
+		}
+		if *p != "" && !filepath.IsAbs(*p) {
+			*p = filepath.Join(dir, *p)
+		}
+	}
+}
+
+// setPath 按顺序用非空的值覆盖target，返回是否被覆盖
+func setPath(target *string, values ...string) bool {
+	var set bool
+	for _, v := range values {
+		if v != "" {
+			*target, set = v, true
+		}
+	}
+	return set
+}
+
+// CheckGamePath 检查游戏根目录是否有效
+func CheckGamePath() error {
+	return checkDir("game", HOI4RootPath, "--game", EnvHOI4RootPath, "game")
+}
+
+// CheckModPath 检查mod根目录是否有效
+func CheckModPath() error {
+	return checkDir("mod", TEWRootPath, "--mod", EnvTEWRootPath, "mod")
+}
+
+func checkDir(name, path, flag, env, key string) error {
+	hint := fmt.Sprintf("set it with %s, $%s or `%s` in %s", flag, env, key, DefaultPathConfigFile)
+	if path == "" {
+		return fmt.Errorf("%s path is not set, %s", name, hint)
+	}
+	info, err := os.Stat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("%s path `%s` does not exist, %s", name, path, hint)
+	} else if err != nil {
+		return err
+	} else if !info.IsDir() {
+		return fmt.Errorf("%s path `%s` is not a directory", name, path)
+	}
+	return nil
+}
@@ -0,0 +1,185 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+// setupPaths 隔离测试环境：空的用户目录、清空的环境变量，并切换到临时目录，返回该目录
+func setupPaths(t *testing.T) string {
+	dir := t.TempDir()
+	home := filepath.Join(dir, "home")
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_DATA_HOME", "")
+	for _, env := range []string{EnvPathConfig, EnvHOI4RootPath, EnvHOI4ModPath, EnvHOI4MyModPath, EnvTEWRootPath, EnvCountriesPath, EnvStatePath, EnvTemplatePath} {
+		t.Setenv(env, "")
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.Chdir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	game, hoi4Mod, myMod, mod, countries, state, templates := HOI4RootPath, HOI4ModPath, HOI4MyModPath, TEWRootPath, CountriesPath, StatePath, TemplatePath
+	t.Cleanup(func() {
+		_ = os.Chdir(wd)
+		HOI4RootPath, HOI4ModPath, HOI4MyModPath, TEWRootPath, CountriesPath, StatePath, TemplatePath = game, hoi4Mod, myMod, mod, countries, state, templates
+	})
+	return dir
+}
+
+func writeFile(t *testing.T, path string, data string) {
+	t.Helper()
+	err := os.MkdirAll(filepath.Dir(path), 0777)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.WriteFile(path, []byte(data), 0666)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoadPaths(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("auto detection paths are only tested on linux")
+	}
+	type paths struct {
+		game, hoi4Mod, myMod, mod, countries string
+	}
+	tests := []struct {
+		name  string
+		yaml  string
+		env   map[string]string
+		flags PathConfig
+		want  paths
+	}{
+		{
+			name: "auto detect",
+			want: paths{game: "home/.steam/steam/steamapps/common/Hearts of Iron IV", hoi4Mod: "home/.local/share/Paradox Interactive/Hearts of Iron IV/mod", myMod: "mod", mod: "mod/TheEmptyWorld", countries: ""},
+		},
+		{
+			name: "yaml over auto detect",
+			yaml: "game: yaml_game\nhoi4_mod: yaml_hoi4_mod\nmy_mod: yaml_my_mod\ncountries: yaml.json\n",
+			want: paths{game: "yaml_game", hoi4Mod: "yaml_hoi4_mod", myMod: "yaml_my_mod", mod: "yaml_my_mod/TheEmptyWorld", countries: "yaml.json"},
+		},
+		{
+			name: "yaml mod over my_mod",
+			yaml: "my_mod: yaml_my_mod\nmod: yaml_mod\n",
+			want: paths{game: "home/.steam/steam/steamapps/common/Hearts of Iron IV", hoi4Mod: "home/.local/share/Paradox Interactive/Hearts of Iron IV/mod", myMod: "yaml_my_mod", mod: "yaml_mod", countries: ""},
+		},
+		{
+			name: "env over yaml",
+			yaml: "game: yaml_game\nhoi4_mod: yaml_hoi4_mod\nmod: yaml_mod\ncountries: yaml.json\n",
+			env:  map[string]string{EnvHOI4RootPath: "env_game", EnvHOI4ModPath: "env_hoi4_mod", EnvHOI4MyModPath: "env_my_mod", EnvCountriesPath: "env.json"},
+			want: paths{game: "env_game", hoi4Mod: "env_hoi4_mod", myMod: "env_my_mod", mod: "env_my_mod/TheEmptyWorld", countries: "env.json"},
+		},
+		{
+			name: "env mod over env my_mod",
+			env:  map[string]string{EnvHOI4MyModPath: "env_my_mod", EnvTEWRootPath: "env_mod"},
+			want: paths{game: "home/.steam/steam/steamapps/common/Hearts of Iron IV", hoi4Mod: "home/.local/share/Paradox Interactive/Hearts of Iron IV/mod", myMod: "env_my_mod", mod: "env_mod", countries: ""},
+		},
+		{
+			name:  "flags over env",
+			yaml:  "game: yaml_game\nmod: yaml_mod\n",
+			env:   map[string]string{EnvHOI4RootPath: "env_game", EnvTEWRootPath: "env_mod"},
+			flags: PathConfig{Game: "flag_game", Mod: "flag_mod"},
+			want:  paths{game: "flag_game", hoi4Mod: "home/.local/share/Paradox Interactive/Hearts of Iron IV/mod", myMod: "mod", mod: "flag_mod", countries: ""},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := setupPaths(t)
+			for _, p := range []string{
+				filepath.Join(dir, "home", ".steam", "steam", "steamapps", "common", "Hearts of Iron IV"),
+				filepath.Join(dir, "home", ".local", "share", "Paradox Interactive", "Hearts of Iron IV", "mod"),
+			} {
+				err := os.MkdirAll(p, 0777)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+			if tt.yaml != "" {
+				writeFile(t, filepath.Join(dir, DefaultPathConfigFile), tt.yaml)
+			}
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+
+			err := LoadPaths("", tt.flags)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// 配置文件与自动探测得到的是绝对路径，统一转换为相对于临时目录的路径比较
+			rel := func(p string) string {
+				if r, err := filepath.Rel(dir, p); err == nil && filepath.IsAbs(p) {
+					return filepath.ToSlash(r)
+				}
+				return filepath.ToSlash(p)
+			}
+			got := paths{game: rel(HOI4RootPath), hoi4Mod: rel(HOI4ModPath), myMod: rel(HOI4MyModPath), mod: rel(TEWRootPath), countries: rel(CountriesPath)}
+			if got != tt.want {
+				t.Fatalf("expect %+v, got %+v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestLoadPathsConfigFile(t *testing.T) {
+	dir := setupPaths(t)
+	writeFile(t, filepath.Join(dir, "conf", "paths.yaml"), "mod: ../TheEmptyWorld\ngame: ~/hoi4\ntemplates: templates\nstate: /tmp/tew\n")
+	t.Setenv(EnvPathConfig, filepath.Join(dir, "conf", "paths.yaml"))
+	err := LoadPaths("", PathConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if TEWRootPath != filepath.Join(dir, "TheEmptyWorld") || HOI4RootPath != filepath.Join(dir, "home", "hoi4") {
+		t.Fatalf("unexpected paths %s, %s", TEWRootPath, HOI4RootPath)
+	}
+	if TemplatePath != filepath.Join(dir, "conf", "templates") || StatePath != "/tmp/tew" {
+		t.Fatalf("unexpected paths %s, %s", TemplatePath, StatePath)
+	}
+	t.Setenv(EnvTemplatePath, "env_templates")
+	err = LoadPaths("", PathConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if TemplatePath != "env_templates" {
+		t.Fatalf("expect template path from env, got %s", TemplatePath)
+	}
+
+	err = LoadPaths(filepath.Join(dir, "missing.yaml"), PathConfig{})
+	if err == nil {
+		t.Fatal("expect error for a missing explicit config file")
+	}
+}
+
+func TestLoadPathsDefaultCountries(t *testing.T) {
+	dir := setupPaths(t)
+	// 在仓库之外运行时，通过配置文件所在目录找到countries.json
+	repo := filepath.Join(dir, "repo")
+	writeFile(t, filepath.Join(repo, DefaultPathConfigFile), "mod: mod\n")
+	writeFile(t, filepath.Join(repo, "config", "countries.json"), "[]")
+	err := LoadPaths(filepath.Join(repo, DefaultPathConfigFile), PathConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if CountriesPath != filepath.Join(repo, "config", "countries.json") {
+		t.Fatalf("expect countries.json next to the config file, got %s", CountriesPath)
+	}
+
+	// 当前目录下的countries.json
+	writeFile(t, filepath.Join(dir, "config", "countries.json"), "[]")
+	err = LoadPaths("", PathConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if CountriesPath != filepath.Join("config", "countries.json") {
+		t.Fatalf("expect countries.json in the working directory, got %s", CountriesPath)
+	}
+}
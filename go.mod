@@ -11,4 +11,12 @@ require (
 
 require github.com/lucasb-eyer/go-colorful v1.2.0
 
-require golang.org/x/sync v0.7.0
+require (
+	github.com/spf13/cobra v1.8.1
+	gopkg.in/yaml.v3 v3.0.1
+)
+
+require (
+	github.com/inconshreveable/mousetrap v1.1.0 // indirect
+	github.com/spf13/pflag v1.0.5 // indirect
+)
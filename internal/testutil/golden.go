@@ -0,0 +1,64 @@
+// Package testutil 测试用的公共工具
+package testutil
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/kkkunny/TEW-hoi4/util"
+)
+
+// updateFlag 使用`go test -update`时用当前输出重新生成golden文件
+var updateFlag = flag.Bool("update", false, "update golden files")
+
+// UpdateEnv 设置为1时同样重新生成golden文件，可用于`go test ./...`中不导入本包、不接受-update参数的测试包
+const UpdateEnv = "TEW_UPDATE_GOLDEN"
+
+func update() bool {
+	return *updateFlag || os.Getenv(UpdateEnv) == "1"
+}
+
+// ModPath 仓库中testdata下的测试用mod根目录
+func ModPath() string {
+	_, file, _, _ := runtime.Caller(0)
+	return filepath.Join(filepath.Dir(file), "..", "..", "testdata", "TheEmptyWorld")
+}
+
+// Golden 将got与调用方包下testdata/name.golden比较，使用-update参数时改为写入该文件
+func Golden(t testing.TB, name string, got []byte) {
+	t.Helper()
+	path := filepath.Join("testdata", name+".golden")
+	if update() {
+		err := os.MkdirAll(filepath.Dir(path), 0777)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = os.WriteFile(path, got, 0666)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("%s (run `go test -update` to create it)", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("output differs from %s (run `go test -update` to accept):\n%s", path, util.UnifiedDiff(path, "got", string(want), string(got)))
+	}
+}
+
+// GoldenJSON 将v编码为缩进的json后与golden文件比较
+func GoldenJSON(t testing.TB, name string, v any) {
+	t.Helper()
+	data, err := json.MarshalIndent(v, "", "\t")
+	if err != nil {
+		t.Fatal(err)
+	}
+	Golden(t, name, append(data, '\n'))
+}
@@ -3,66 +3,30 @@ package common
 import (
 	"fmt"
 	"image/color"
-	"io"
 	"os"
 	"path/filepath"
-	"regexp"
-	"strconv"
 	"strings"
 
-	stlbasic "github.com/kkkunny/stl/basic"
 	"github.com/kkkunny/stl/container/optional"
 	stlslices "github.com/kkkunny/stl/container/slices"
 
+	"github.com/kkkunny/TEW-hoi4/parser/pdx"
 	"github.com/kkkunny/TEW-hoi4/util"
 )
 
 type CountryDef struct {
-	GraphicalCulture   optional.Optional[string]      `json:"graphical_culture,omitempty"`
-	GraphicalCulture2D optional.Optional[string]      `json:"graphical_culture_2d,omitempty"`
-	Color              optional.Optional[color.Color] `json:"color,omitempty"`
+	GraphicalCulture   optional.Optional[string]      `json:"graphical_culture,omitempty" pdx:"graphical_culture"`
+	GraphicalCulture2D optional.Optional[string]      `json:"graphical_culture_2d,omitempty" pdx:"graphical_culture_2d"`
+	Color              optional.Optional[color.Color] `json:"color,omitempty" pdx:"color,rgb"`
 }
 
 func ParseCountryDef(path string) (*CountryDef, error) {
-	file, err := os.Open(path)
+	var def CountryDef
+	err := pdx.UnmarshalFile(path, &def)
 	if err != nil {
 		return nil, err
 	}
-	defer file.Close()
-
-	data, err := io.ReadAll(file)
-	if err != nil {
-		return nil, err
-	}
-	content := string(data)
-	graphicalCulture := stlslices.Last(regexp.MustCompile(`graphical_culture\s*=\s*(.+)`).FindStringSubmatch(content))
-	graphicalCulture2D := stlslices.Last(regexp.MustCompile(`graphical_culture_2d\s*=\s*(.+)`).FindStringSubmatch(content))
-	colorMatches := regexp.MustCompile(`color\s*=\s*(\w*?)\s*{\s*([\d.]+?)\s*([\d.]+?)\s*([\d.]+?)\s*}`).FindStringSubmatch(content)
-	var colorVal optional.Optional[color.Color]
-	if len(colorMatches) > 0 {
-		v1, err := strconv.ParseFloat(colorMatches[2], 64)
-		if err != nil {
-			return nil, err
-		}
-		v2, err := strconv.ParseFloat(colorMatches[3], 64)
-		if err != nil {
-			return nil, err
-		}
-		v3, err := strconv.ParseFloat(colorMatches[4], 64)
-		if err != nil {
-			return nil, err
-		}
-		clr, err := util.NewColorByMode(colorMatches[1], v1, v2, v3)
-		if err != nil {
-			return nil, err
-		}
-		colorVal = optional.Some(clr)
-	}
-	return &CountryDef{
-		GraphicalCulture:   stlbasic.Ternary(graphicalCulture == "", optional.None[string](), optional.Some(graphicalCulture)),
-		GraphicalCulture2D: stlbasic.Ternary(graphicalCulture2D == "", optional.None[string](), optional.Some(graphicalCulture2D)),
-		Color:              colorVal,
-	}, nil
+	return &def, nil
 }
 
 func (c *CountryDef) Encode() string {
@@ -83,62 +47,29 @@ func (c *CountryDef) Encode() string {
 }
 
 type CountryColor struct {
-	Country string      `json:"country"`
-	Color   color.Color `json:"color"`
-	ColorUI color.Color `json:"color_ui"`
+	Country string      `json:"country" pdx:"-"`
+	Color   color.Color `json:"color" pdx:"color,rgb"`
+	ColorUI color.Color `json:"color_ui" pdx:"color_ui,rgb"`
 }
 
 func ParseCountryColors(path string) ([]*CountryColor, error) {
-	file, err := os.Open(path)
-	if err != nil {
-		return nil, err
-	}
-	defer file.Close()
-
-	data, err := io.ReadAll(file)
+	file, err := pdx.ParseFile(path)
 	if err != nil {
 		return nil, err
 	}
-	content := strings.ReplaceAll(string(data), "\n", " ")
-	countryColorMatchesList := regexp.MustCompile(`(\w+)\s*=\s*{\s*color\s*=\s*(\w*?)\s*{\s*([\d.]+?)\s*([\d.]+?)\s*([\d.]+?)\s*}\s*color_ui\s*=\s*(\w*?)\s*{\s*([\d.]+?)\s*([\d.]+?)\s*([\d.]+?)\s*}`).FindAllStringSubmatch(content, -1)
-	return stlslices.MapError(countryColorMatchesList, func(_ int, countryColorMatches []string) (*CountryColor, error) {
-		v1, err := strconv.ParseFloat(countryColorMatches[3], 64)
-		if err != nil {
-			return nil, err
-		}
-		v2, err := strconv.ParseFloat(countryColorMatches[4], 64)
-		if err != nil {
-			return nil, err
-		}
-		v3, err := strconv.ParseFloat(countryColorMatches[5], 64)
-		if err != nil {
-			return nil, err
-		}
-		clr, err := util.NewColorByMode(countryColorMatches[2], v1, v2, v3)
+	return stlslices.MapError(file.Body.Assignments(), func(_ int, a *pdx.Assignment) (*CountryColor, error) {
+		cc := &CountryColor{Country: a.Name()}
+		err := pdx.UnmarshalNode(a, cc)
 		if err != nil {
 			return nil, err
 		}
-		v1, err = strconv.ParseFloat(countryColorMatches[7], 64)
-		if err != nil {
-			return nil, err
-		}
-		v2, err = strconv.ParseFloat(countryColorMatches[8], 64)
-		if err != nil {
-			return nil, err
+		if cc.Color == nil {
+			return nil, fmt.Errorf("%s: country `%s` missing color", a.Pos(), cc.Country)
 		}
-		v3, err = strconv.ParseFloat(countryColorMatches[9], 64)
-		if err != nil {
-			return nil, err
-		}
-		clrUI, err := util.NewColorByMode(countryColorMatches[6], v1, v2, v3)
-		if err != nil {
-			return nil, err
+		if cc.ColorUI == nil {
+			cc.ColorUI = cc.Color
 		}
-		return &CountryColor{
-			Country: countryColorMatches[1],
-			Color:   clr,
-			ColorUI: clrUI,
-		}, nil
+		return cc, nil
 	})
 }
 
@@ -1,30 +1,34 @@
 package common
 
 import (
-	"fmt"
 	"path/filepath"
+	"strings"
 	"testing"
 
-	stlmaps "github.com/kkkunny/stl/container/maps"
-
-	"github.com/kkkunny/TEW-hoi4/config"
+	"github.com/kkkunny/TEW-hoi4/internal/testutil"
 )
 
 func TestParseCountries(t *testing.T) {
-	// country, err := ParseCountryDef(filepath.Join(config.HOI4MyModPath, "TheEmptyWorld", "common", "countries", "African.txt"))
-	// if err != nil {
-	// 	panic(err)
-	// }
-	// fmt.Println(country.Encode())
-	// countryColors, err := ParseCountryColors(filepath.Join(config.HOI4MyModPath, "TheEmptyWorld", "common", "countries", "cosmetic.txt"))
-	// if err != nil {
-	// 	panic(err)
-	// }
-	// fmt.Println(stlslices.First(countryColors).Encode())
-	countryDefs, _, err := ParseCountriesDir(filepath.Join(config.HOI4MyModPath, "TheEmptyWorld"))
+	countryDefs, colors, err := ParseCountriesDir(testutil.ModPath())
+	if err != nil {
+		t.Fatal(err)
+	}
+	testutil.GoldenJSON(t, "countries.json", map[string]any{
+		"defs":   countryDefs,
+		"colors": colors,
+	})
+	testutil.Golden(t, "European.txt", []byte(countryDefs["European"].Encode()))
+}
+
+func TestParseCountryColors(t *testing.T) {
+	colors, err := ParseCountryColors(filepath.Join(testutil.ModPath(), "common", "countries", "colors.txt"))
 	if err != nil {
-		panic(err)
+		t.Fatal(err)
+	}
+	var buf strings.Builder
+	for _, cc := range colors {
+		buf.WriteString(cc.Encode())
+		buf.WriteString("\n")
 	}
-	_, v := stlmaps.Random(countryDefs)
-	fmt.Println(v.Encode())
+	testutil.Golden(t, "colors.txt", []byte(buf.String()))
 }
@@ -1,33 +1,32 @@
 package common
 
 import (
-	"fmt"
 	"path/filepath"
+	"strings"
 	"testing"
 
-	stlbasic "github.com/kkkunny/stl/basic"
-
-	"github.com/kkkunny/TEW-hoi4/config"
+	"github.com/kkkunny/TEW-hoi4/internal/testutil"
 )
 
 func TestParseCountryTag(t *testing.T) {
-	// state, err := ParseStateDir(filepath.Join(config.HOI4MyModPath, "TheEmptyWorld"))
-	// if err != nil {
-	// 	panic(err)
-	// }
-	// data, err := json.MarshalIndent(state[:10], "  ", "")
-	// if err != nil {
-	// 	panic(err)
-	// }
-	// fmt.Println(string(data))
-	tags, err := ParseCountryTagsDirNotDynamic(filepath.Join(config.HOI4MyModPath, "TheEmptyWorld"))
+	tags, err := ParseCountryTag(filepath.Join(testutil.ModPath(), "common", "country_tags", "00_countries.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf strings.Builder
+	for _, tag := range tags {
+		buf.WriteString(tag.Encode())
+		buf.WriteString("\n")
+	}
+	testutil.Golden(t, "country_tags.txt", []byte(buf.String()))
+
+	tags, err = ParseCountryTagsDirNotDynamic(testutil.ModPath())
 	if err != nil {
-		panic(err)
+		t.Fatal(err)
 	}
-	for i, tag := range tags {
-		fmt.Printf("\t\t\t\t%s = {\n", stlbasic.Ternary(i == 0, "if", "else_if"))
-		fmt.Printf("\t\t\t\t\tlimit = { original_tag = %s }\n", tag.ID)
-		fmt.Printf("\t\t\t\t\tset_cosmetic_tag = %s_alias_%s\n", tag.ID, "anarchist")
-		fmt.Println("\t\t\t\t}")
+	for _, tag := range tags {
+		if tag.ID == "D01" {
+			t.Errorf("dynamic tag `%s` not filtered", tag.ID)
+		}
 	}
 }
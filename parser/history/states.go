@@ -1,187 +1,413 @@
 package history
 
 import (
-	"bytes"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
 	"regexp"
+	"slices"
 	"strconv"
 	"strings"
 
-	"github.com/kkkunny/stl/container/linkedhashmap"
-	stlmaps "github.com/kkkunny/stl/container/maps"
+	stlbasic "github.com/kkkunny/stl/basic"
+	"github.com/kkkunny/stl/container/optional"
 	stlslices "github.com/kkkunny/stl/container/slices"
 	"golang.org/x/exp/maps"
+
+	"github.com/kkkunny/TEW-hoi4/parser/pdx"
 )
 
 type State struct {
-	ID            int64              `json:"id,omitempty"`
-	Name          string             `json:"name,omitempty"`
-	Manpower      int64              `json:"manpower,omitempty"`
-	Category      string             `json:"state_category,omitempty"`
-	Impassable    bool               `json:"impassable,omitempty"`
-	LocalSupplies float64            `json:"local_supplies"`
-	Resources     map[string]float64 `json:"resources,omitempty"`
-	Provinces     []int64            `json:"provinces,omitempty"`
-	History       struct {
-		Owner             string                     `json:"owner,omitempty"`
-		Cores             []string                   `json:"-"`
-		Claims            []string                   `json:"-"`
-		CommonBuildings   map[string]int64           `json:"buildings,omitempty"`
-		ProvinceBuildings map[int64]map[string]int64 `json:"-"`
-		VictoryPoints     []int64                    `json:"victory_points,omitempty"`
-	} `json:"history,omitempty"`
+	ID                      int64              `json:"id,omitempty" pdx:"id"`
+	Name                    string             `json:"name,omitempty" pdx:"name,quoted"`
+	Manpower                int64              `json:"manpower,omitempty" pdx:"manpower,omitempty"`
+	Category                string             `json:"state_category,omitempty" pdx:"state_category,omitempty"`
+	Impassable              bool               `json:"impassable,omitempty" pdx:"impassable,omitempty"`
+	BuildingsMaxLevelFactor float64            `json:"buildings_max_level_factor,omitempty" pdx:"buildings_max_level_factor,omitempty"`
+	LocalSupplies           float64            `json:"local_supplies" pdx:"local_supplies,omitempty"`
+	Resources               map[string]float64 `json:"resources,omitempty" pdx:"resources,omitempty"`
+	Provinces               []int64            `json:"provinces,omitempty" pdx:"provinces"`
+	History                 StateHistory       `json:"history,omitempty" pdx:"history"`
+	// Rest 其他未建模的语句
+	Rest pdx.Statements `json:"-" pdx:",remain"`
+
+	// file 解析得到的语法树，Encode时只修改发生变化的部分
+	file *pdx.File
 }
 
-func ParseState(path string) (*State, error) {
-	data, err := os.ReadFile(path)
-	if err != nil {
-		return nil, err
+// StateHistory 地区历史，包括开局时的设置与带日期的历史
+type StateHistory struct {
+	Owner                    string                     `json:"owner,omitempty" pdx:"owner,omitempty"`
+	Controller               string                     `json:"controller,omitempty" pdx:"controller,omitempty"`
+	Cores                    []string                   `json:"cores,omitempty" pdx:"add_core_of,multi"`
+	Claims                   []string                   `json:"claims,omitempty" pdx:"add_claim_by,multi"`
+	RemovedCores             []string                   `json:"removed_cores,omitempty" pdx:"remove_core_of,multi"`
+	RemovedClaims            []string                   `json:"removed_claims,omitempty" pdx:"remove_claim_by,multi"`
+	DemilitarizedZone        optional.Optional[bool]    `json:"demilitarized_zone,omitempty" pdx:"set_demilitarized_zone"`
+	ExtraSharedBuildingSlots optional.Optional[int64]   `json:"extra_shared_building_slots,omitempty" pdx:"add_extra_state_shared_building_slots"`
+	CommonBuildings          map[string]int64           `json:"buildings,omitempty" pdx:"-"`
+	ProvinceBuildings        map[int64]map[string]int64 `json:"province_buildings,omitempty" pdx:"-"`
+	VictoryPoints            []VictoryPoint             `json:"victory_points,omitempty" pdx:"victory_points,multi"`
+	// Effects 其他效果语句，如 set_state_flag
+	Effects pdx.Statements `json:"-" pdx:"-"`
+	// Dated 带日期的历史，如 1939.1.1 = { ... }
+	Dated []*DatedStateHistory `json:"dated,omitempty" pdx:"-"`
+}
+
+// DatedStateHistory 在指定日期生效的地区历史
+type DatedStateHistory struct {
+	Date string `json:"date"`
+	StateHistory
+}
+
+// CompareDate 比较两个形如1936.1.1的日期，a早于b时返回负数
+func CompareDate(a, b string) int {
+	return slices.Compare(parseDate(a), parseDate(b))
+}
+
+// parseDate 将日期解析为年、月、日，不合法的部分视为0
+func parseDate(date string) []int {
+	parts := strings.Split(date, ".")
+	res := make([]int, 3)
+	for i := 0; i < len(parts) && i < len(res); i++ {
+		res[i], _ = strconv.Atoi(parts[i])
 	}
-	data = bytes.ReplaceAll(data, []byte{'\n'}, []byte{','})
-	data = bytes.ReplaceAll(data, []byte{'\r'}, nil)
-	data = regexp.MustCompile(`\{.*}`).Find(data)
-	data = bytes.ReplaceAll(data, []byte{'"'}, []byte{' '})
-
-	content := regexp.MustCompile(`(\w+)\b`).ReplaceAllString(string(data), "\"${1}\"")
-	content = regexp.MustCompile(`"(\d+)"`).ReplaceAllString(content, "${1}")
-	content = strings.ReplaceAll(content, " ", ",")
-	content = strings.ReplaceAll(content, "\t", ",")
-	content = regexp.MustCompile(`,+`).ReplaceAllString(content, ",")
-	content = strings.ReplaceAll(content, "{,", "{")
-	content = strings.ReplaceAll(content, ",}", "}")
-	content = strings.ReplaceAll(content, "\"yes\"", "true")
-	content = strings.ReplaceAll(content, "\"no\"", "false")
-	content = regexp.MustCompile(`,?=,?`).ReplaceAllString(content, ":")
-
-	coreResults := regexp.MustCompile(`"add_core_of":"(.+?)"`).FindAllStringSubmatch(content, -1)
-	cores := stlslices.Map(coreResults, func(_ int, e []string) string {
-		return e[1]
+	return res
+}
+
+// At 返回date时（含当天）生效的拥有者、控制者、核心与宣称，带日期的历史按日期先后依次应用
+// 返回的Cores和Claims已去掉被移除的国家，其他字段为空
+func (h StateHistory) At(date string) StateHistory {
+	res := StateHistory{Owner: h.Owner, Controller: h.Controller}
+	apply := func(h *StateHistory) {
+		if h.Owner != "" {
+			res.Owner = h.Owner
+		}
+		if h.Controller != "" {
+			res.Controller = h.Controller
+		}
+		res.Cores = addTags(res.Cores, h.Cores, h.RemovedCores)
+		res.Claims = addTags(res.Claims, h.Claims, h.RemovedClaims)
+	}
+	apply(&h)
+
+	dated := slices.Clone(h.Dated)
+	slices.SortStableFunc(dated, func(a, b *DatedStateHistory) int {
+		return CompareDate(a.Date, b.Date)
 	})
-	content = regexp.MustCompile(`,?"add_core_of":"(.+?)"`).ReplaceAllString(content, "")
+	for _, d := range dated {
+		if CompareDate(d.Date, date) > 0 {
+			break
+		}
+		apply(&d.StateHistory)
+	}
+	return res
+}
 
-	claimResults := regexp.MustCompile(`"add_claim_by":"(.+?)"`).FindAllStringSubmatch(content, -1)
-	claims := stlslices.Map(claimResults, func(_ int, e []string) string {
-		return e[1]
+// addTags 向tags中加入added并去掉removed中的国家
+func addTags(tags, added, removed []string) []string {
+	for _, tag := range added {
+		if !slices.Contains(tags, tag) {
+			tags = append(tags, tag)
+		}
+	}
+	return slices.DeleteFunc(tags, func(tag string) bool {
+		return slices.Contains(removed, tag)
 	})
-	content = regexp.MustCompile(`,?"add_claim_by":"(.+?)"`).ReplaceAllString(content, "")
+}
+
+// stateHistoryFields 与StateHistory相同但不带自定义编解码方法，用于处理有pdx标签的字段
+type stateHistoryFields StateHistory
 
-	content = regexp.MustCompile(`\{([\d,]+)}`).ReplaceAllString(content, "[${1}]")
-	content = regexp.MustCompile(`(\d+):`).ReplaceAllString(content, "\"${1}\":")
+var stateHistoryKeys = stlslices.ToMap(pdx.FieldKeys(stateHistoryFields{}), func(k string) (string, struct{}) {
+	return k, struct{}{}
+})
 
-	provinceBuildingResults := regexp.MustCompile(`"(\d+)":(\{.+?})`).FindAllStringSubmatch(content, -1)
-	provinceBuildingList, err := stlslices.MapError(provinceBuildingResults, func(_ int, e []string) (map[int64]map[string]int64, error) {
-		provinceID, err := strconv.ParseInt(e[1], 10, 64)
+var dateRegexp = regexp.MustCompile(`^\d+\.\d+\.\d+$`)
+
+func isDate(key string) bool {
+	return dateRegexp.MatchString(key)
+}
+
+// isEffect 是否为需要保存在Effects中的语句
+func isEffect(stmt pdx.Statement) bool {
+	a, ok := stmt.(*pdx.Assignment)
+	if !ok {
+		return true
+	}
+	if _, ok = stateHistoryKeys[a.Name()]; ok {
+		return false
+	}
+	return a.Name() != "buildings" && !isDate(a.Name())
+}
+
+func (h *StateHistory) UnmarshalPDX(value pdx.Value) error {
+	block, ok := value.(*pdx.Block)
+	if !ok {
+		return fmt.Errorf("%s: expect a block", value.Pos())
+	}
+	err := pdx.UnmarshalNode(block, (*stateHistoryFields)(h))
+	if err != nil {
+		return err
+	}
+
+	h.CommonBuildings, h.ProvinceBuildings, h.Effects, h.Dated = nil, nil, nil, nil
+	for _, stmt := range block.Body {
+		if isEffect(stmt) {
+			h.Effects = append(h.Effects, stmt)
+			continue
+		}
+		a := stmt.(*pdx.Assignment)
+		switch {
+		case a.Name() == "buildings":
+			err = h.parseBuildings(a.Value)
+		case isDate(a.Name()):
+			dated := &DatedStateHistory{Date: a.Name()}
+			err = dated.StateHistory.UnmarshalPDX(a.Value)
+			h.Dated = append(h.Dated, dated)
+		}
 		if err != nil {
-			return nil, err
+			return err
+		}
+	}
+	return nil
+}
+
+func (h *StateHistory) parseBuildings(value pdx.Value) error {
+	block, ok := value.(*pdx.Block)
+	if !ok {
+		return fmt.Errorf("%s: expect a block", value.Pos())
+	}
+	for _, a := range block.Body.Assignments() {
+		if provinceID, err := a.Key.Int(); err == nil {
+			buildings := make(map[string]int64)
+			err = pdx.UnmarshalNode(a, &buildings)
+			if err != nil {
+				return err
+			}
+			if h.ProvinceBuildings == nil {
+				h.ProvinceBuildings = make(map[int64]map[string]int64)
+			}
+			h.ProvinceBuildings[provinceID] = buildings
+			continue
 		}
-		buildings := make(map[string]int64)
-		err = json.Unmarshal([]byte(e[2]), &buildings)
+		var level int64
+		err := pdx.UnmarshalNode(a, &level)
 		if err != nil {
-			return nil, err
+			return err
 		}
-		return map[int64]map[string]int64{provinceID: buildings}, nil
-	})
-	provinceBuildings := make(map[int64]map[string]int64, len(provinceBuildingList))
-	for _, provinceBuilding := range provinceBuildingList {
-		k := stlslices.First(maps.Keys(provinceBuilding))
-		provinceBuildings[k] = provinceBuilding[k]
+		if h.CommonBuildings == nil {
+			h.CommonBuildings = make(map[string]int64)
+		}
+		h.CommonBuildings[a.Name()] = level
+	}
+	return nil
+}
+
+func (h StateHistory) buildingsValue() (pdx.Value, error) {
+	if len(h.CommonBuildings) == 0 && len(h.ProvinceBuildings) == 0 {
+		return nil, nil
+	}
+	block := pdx.NewBlock()
+	common, err := pdx.MarshalValue(h.CommonBuildings)
+	if err != nil {
+		return nil, err
+	}
+	block.Append(common.(*pdx.Block).Body...)
+	provinces, err := pdx.MarshalValue(h.ProvinceBuildings)
+	if err != nil {
+		return nil, err
+	}
+	block.Append(provinces.(*pdx.Block).Body...)
+	return block, nil
+}
+
+func (h StateHistory) MarshalPDX() (pdx.Value, error) {
+	value, err := pdx.MarshalValue(stateHistoryFields(h))
+	if err != nil {
+		return nil, err
 	}
+	block := value.(*pdx.Block)
+	buildings, err := h.buildingsValue()
 	if err != nil {
 		return nil, err
 	}
-	content = regexp.MustCompile(`,?"(\d+)":(\{.+?})`).ReplaceAllString(content, "")
+	if buildings != nil {
+		block.Append(pdx.NewAssignment("buildings", buildings))
+	}
+	block.Append(h.Effects...)
+	for _, dated := range h.Dated {
+		datedValue, err := dated.StateHistory.MarshalPDX()
+		if err != nil {
+			return nil, err
+		}
+		block.Append(pdx.NewAssignment(dated.Date, datedValue))
+	}
+	return block, nil
+}
 
-	content = strings.ReplaceAll(content, "{,", "{")
-	content = strings.ReplaceAll(content, ",}", "}")
+func (h *StateHistory) PatchPDX(block *pdx.Block) error {
+	err := pdx.Patch(block, (*stateHistoryFields)(h))
+	if err != nil {
+		return err
+	}
+	if err = h.patchBuildings(block); err != nil {
+		return err
+	}
 
+	for _, stmt := range slices.Clone(block.Body) {
+		if isEffect(stmt) && !slices.Contains(h.Effects, stmt) {
+			block.Remove(stmt)
+		}
+	}
+	for _, stmt := range h.Effects {
+		if !slices.Contains(block.Body, stmt) {
+			pdx.Reformat(stmt, block.Depth())
+			block.Append(stmt)
+		}
+	}
+	return h.patchDated(block)
+}
+
+func (h *StateHistory) patchBuildings(block *pdx.Block) error {
+	old := block.Body.Get("buildings")
+	if old == nil {
+		buildings, err := h.buildingsValue()
+		if err != nil || buildings == nil {
+			return err
+		}
+		a := pdx.NewAssignment("buildings", buildings)
+		pdx.Reformat(a, block.Depth())
+		block.Append(a)
+		return nil
+	}
+	if len(h.CommonBuildings) == 0 && len(h.ProvinceBuildings) == 0 {
+		block.Remove(old)
+		return nil
+	}
+	buildingsBlock, ok := old.Value.(*pdx.Block)
+	if !ok {
+		return fmt.Errorf("%s: expect a block", old.Value.Pos())
+	}
+
+	seenCommon := make(map[string]bool)
+	seenProvince := make(map[int64]bool)
+	for _, a := range buildingsBlock.Body.Assignments() {
+		if provinceID, err := a.Key.Int(); err == nil {
+			buildings, ok := h.ProvinceBuildings[provinceID]
+			if !ok || seenProvince[provinceID] {
+				buildingsBlock.Remove(a)
+				continue
+			}
+			seenProvince[provinceID] = true
+			if err = pdx.Patch(a, buildings); err != nil {
+				return err
+			}
+			continue
+		}
+		level, ok := h.CommonBuildings[a.Name()]
+		if !ok || seenCommon[a.Name()] {
+			buildingsBlock.Remove(a)
+			continue
+		}
+		seenCommon[a.Name()] = true
+		if oldLevel, err := a.Value.(*pdx.Scalar).Int(); err != nil || oldLevel != level {
+			a.SetValue(pdx.NewInt(level))
+		}
+	}
+
+	for _, name := range stlslices.Sort(maps.Keys(h.CommonBuildings)) {
+		if !seenCommon[name] {
+			buildingsBlock.Append(pdx.NewAssignment(name, pdx.NewInt(h.CommonBuildings[name])))
+		}
+	}
+	for _, provinceID := range stlslices.Sort(maps.Keys(h.ProvinceBuildings)) {
+		if seenProvince[provinceID] {
+			continue
+		}
+		buildings, err := pdx.MarshalValue(h.ProvinceBuildings[provinceID])
+		if err != nil {
+			return err
+		}
+		a := pdx.NewAssignment(strconv.FormatInt(provinceID, 10), buildings)
+		pdx.Reformat(a, buildingsBlock.Depth())
+		buildingsBlock.Append(a)
+	}
+	return nil
+}
+
+func (h *StateHistory) patchDated(block *pdx.Block) error {
+	used := make([]bool, len(h.Dated))
+	for _, a := range block.Body.Assignments() {
+		if !isDate(a.Name()) {
+			continue
+		}
+		i := slices.IndexFunc(h.Dated, func(dated *DatedStateHistory) bool {
+			return dated.Date == a.Name()
+		})
+		for i >= 0 && used[i] {
+			next := slices.IndexFunc(h.Dated[i+1:], func(dated *DatedStateHistory) bool {
+				return dated.Date == a.Name()
+			})
+			i = stlbasic.Ternary(next < 0, -1, i+1+next)
+		}
+		datedBlock, ok := a.Value.(*pdx.Block)
+		if i < 0 || !ok {
+			block.Remove(a)
+			continue
+		}
+		used[i] = true
+		if err := h.Dated[i].StateHistory.PatchPDX(datedBlock); err != nil {
+			return err
+		}
+	}
+	for i, dated := range h.Dated {
+		if used[i] {
+			continue
+		}
+		value, err := dated.StateHistory.MarshalPDX()
+		if err != nil {
+			return err
+		}
+		a := pdx.NewAssignment(dated.Date, value)
+		pdx.Reformat(a, block.Depth())
+		block.Append(a)
+	}
+	return nil
+}
+
+func ParseState(path string) (*State, error) {
+	file, err := pdx.ParseFile(path)
+	if err != nil {
+		return nil, err
+	}
+	a := file.Body.Get("state")
+	if a == nil {
+		return nil, errors.New("missing `state`")
+	}
 	var state State
-	err = json.Unmarshal([]byte(content), &state)
+	err = pdx.UnmarshalNode(a, &state)
 	if err != nil {
 		return nil, err
 	}
-	state.History.Cores = cores
-	state.History.Claims = claims
-	state.History.ProvinceBuildings = provinceBuildings
+	state.file = file
 	return &state, nil
 }
 
-type innerState struct {
-	ID            int64                                    `json:"id,omitempty"`
-	Name          string                                   `json:"name,omitempty"`
-	Manpower      int64                                    `json:"manpower,omitempty"`
-	Category      string                                   `json:"state_category,omitempty"`
-	Impassable    bool                                     `json:"impassable,omitempty"`
-	LocalSupplies float64                                  `json:"local_supplies"`
-	Resources     map[string]float64                       `json:"resources,omitempty"`
-	Provinces     []int64                                  `json:"provinces,omitempty"`
-	History       linkedhashmap.LinkedHashMap[string, any] `json:"history,omitempty"`
-}
-
-func (state *State) Encode() string {
-	cache := &innerState{
-		ID:            state.ID,
-		Name:          state.Name,
-		Manpower:      state.Manpower,
-		Category:      state.Category,
-		Impassable:    state.Impassable,
-		LocalSupplies: state.LocalSupplies,
-		Resources:     state.Resources,
-		Provinces:     state.Provinces,
-		History:       linkedhashmap.NewLinkedHashMap[string, any](),
-	}
-	cache.History.Set("owner", state.History.Owner)
-	if len(state.History.Cores) != 0 {
-		for i, core := range state.History.Cores {
-			cache.History.Set(fmt.Sprintf("add_core_of_%d", i), core)
-		}
-	}
-	if len(state.History.Claims) != 0 {
-		for i, claim := range state.History.Claims {
-			cache.History.Set(fmt.Sprintf("add_claim_by_%d", i), claim)
-		}
-	}
-	vicPoints := stlslices.Filter(stlslices.Map(state.History.VictoryPoints, func(i int, e int64) string {
-		if i%2 != 0 {
-			return ""
-		}
-		return fmt.Sprintf("%d %d", state.History.VictoryPoints[i], state.History.VictoryPoints[i+1])
-	}), func(_ int, e string) bool {
-		return e != ""
-	})
-	if len(vicPoints) != 0 {
-		cache.History.Set("victory_points", vicPoints)
+// Path 返回地区文件路径，不是由ParseState得到时返回空字符串
+func (state *State) Path() string {
+	if state.file == nil {
+		return ""
 	}
-	if len(state.History.CommonBuildings) != 0 {
-		buildings := stlmaps.Map(state.History.CommonBuildings, func(k string, v int64) (string, any) {
-			return k, v
-		})
-		cache.History.Set("buildings", buildings)
-	}
-	if len(state.History.ProvinceBuildings) != 0 {
-		if !cache.History.ContainKey("buildings") {
-			cache.History.Set("buildings", make(map[string]any))
-		}
-		buildings := cache.History.Get("buildings").(map[string]any)
-		for provinceID, building := range state.History.ProvinceBuildings {
-			buildings[strconv.FormatInt(provinceID, 10)] = building
-		}
-	}
-
-	data, _ := json.MarshalIndent(cache, "", "  ")
-	content := strings.ReplaceAll(string(data), ":", " =")
-	content = strings.ReplaceAll(content, ",", "")
-	content = strings.ReplaceAll(content, " true", " yes")
-	content = strings.ReplaceAll(content, " false", " no")
-	content = strings.ReplaceAll(content, "\"", "")
-	content = regexp.MustCompile(`name = (.+)`).ReplaceAllString(content, "name = \"${1}\"")
-	content = regexp.MustCompile(`add_core_of_\d+`).ReplaceAllString(content, "add_core_of")
-	content = regexp.MustCompile(`add_claim_by_\d+`).ReplaceAllString(content, "add_claim_by")
-	content = strings.ReplaceAll(content, "[", "{")
-	content = strings.ReplaceAll(content, "]", "}")
-	return "state = " + content
+	return state.file.Filename
+}
+
+// Encode 编码为地区文件，由ParseState得到的地区只会修改发生变化的语句
+func (state *State) Encode() ([]byte, error) {
+	return pdx.EncodeFile(state.file, &struct {
+		State *State `pdx:"state"`
+	}{State: state})
 }
 
 func ParseStateDir(modPath string) ([]*State, error) {
@@ -1,26 +1,131 @@
 package history
 
 import (
-	"fmt"
+	"errors"
+	"os"
 	"path/filepath"
+	"slices"
+	"strings"
 	"testing"
 
-	"github.com/kkkunny/TEW-hoi4/config"
+	"github.com/kkkunny/TEW-hoi4/internal/testutil"
 )
 
 func TestParseState(t *testing.T) {
-	// state, err := ParseStateDir(filepath.Join(config.HOI4MyModPath, "TheEmptyWorld"))
-	// if err != nil {
-	// 	panic(err)
-	// }
-	// data, err := json.MarshalIndent(state[:10], "  ", "")
-	// if err != nil {
-	// 	panic(err)
-	// }
-	// fmt.Println(string(data))
-	state, err := ParseState(filepath.Join(config.HOI4MyModPath, "TheEmptyWorld", "history", "states", "4885-STATE_4885.txt"))
+	states, err := ParseStateDir(testutil.ModPath())
 	if err != nil {
-		panic(err)
+		t.Fatal(err)
 	}
-	fmt.Println(state.Encode())
+	if len(states) != 2 {
+		t.Fatalf("expect 2 states, got %d", len(states))
+	}
+	testutil.GoldenJSON(t, "states.json", states)
+
+	for _, state := range states {
+		src, err := os.ReadFile(state.Path())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := encodeState(t, state); got != string(src) {
+			t.Errorf("%s: Encode is not lossless:\n%s", state.Path(), got)
+		}
+		state.History.Owner = "ITA"
+		state.History.Cores = append(state.History.Cores, "ITA")
+		testutil.Golden(t, filepath.Base(state.Path()), []byte(encodeState(t, state)))
+	}
+}
+
+const testStateSource = "\xEF\xBB\xBF\nstate={\n\tid=4885\n\tname=\"STATE_4885\" # Paris\n\tmanpower = 2500000\n\n\tstate_category = megalopolis\n\n\thistory={\n\t\towner = FRA\n\t\tvictory_points = {\n\t\t\t11506 50 \n\t\t}\n\t\tbuildings = {\n\t\t\tinfrastructure = 4\n\t\t\t11506 = {\n\t\t\t\tnaval_base = 3\n\t\t\t}\n\t\t}\n\t\tadd_core_of = FRA\n\t\tset_state_flag = paris_flag\n\t\t1939.1.1 = {\n\t\t\tcontroller = GER\n\t\t\tremove_core_of = FRA\n\t\t}\n\t}\n\n\tprovinces={\n\t\t11506 6552 \n\t}\n\tlocal_supplies=10.0 \n}\n"
+
+func TestStateEncode(t *testing.T) {
+	fp := filepath.Join(t.TempDir(), "4885-STATE_4885.txt")
+	err := os.WriteFile(fp, []byte(testStateSource), 0666)
+	if err != nil {
+		t.Fatal(err)
+	}
+	state, err := ParseState(fp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res := encodeState(t, state); res != testStateSource {
+		t.Fatalf("encode is not lossless:\n%s", res)
+	}
+	if len(state.History.Effects) != 1 || len(state.History.Dated) != 1 || state.History.Dated[0].Controller != "GER" {
+		t.Fatalf("unexpected history %+v", state.History)
+	}
+
+	state.History.Cores = append(state.History.Cores, "WRM")
+	state.History.Dated[0].Owner = "GER"
+	expect := strings.Replace(testStateSource, "\t\tadd_core_of = FRA\n", "\t\tadd_core_of = FRA\n\t\tadd_core_of = WRM\n", 1)
+	expect = strings.Replace(expect, "\t\t\tcontroller = GER\n", "\t\t\towner = GER\n\t\t\tcontroller = GER\n", 1)
+	if res := encodeState(t, state); res != expect {
+		t.Fatalf("unexpected encode result:\n%s", res)
+	}
+}
+
+func TestStateHistoryAt(t *testing.T) {
+	h := StateHistory{
+		Owner: "FRA",
+		Cores: []string{"FRA"},
+		Dated: []*DatedStateHistory{
+			{Date: "1939.1.1", StateHistory: StateHistory{Owner: "GER", RemovedCores: []string{"FRA"}}},
+			{Date: "1936.3.7", StateHistory: StateHistory{Cores: []string{"ITA"}, Claims: []string{"GER"}}},
+			{Date: "1936.10.1", StateHistory: StateHistory{RemovedClaims: []string{"GER"}}},
+		},
+	}
+	tests := []struct {
+		date   string
+		owner  string
+		cores  []string
+		claims []string
+	}{
+		{date: "1936.1.1", owner: "FRA", cores: []string{"FRA"}},
+		{date: "1936.3.7", owner: "FRA", cores: []string{"FRA", "ITA"}, claims: []string{"GER"}},
+		{date: "1936.9.30", owner: "FRA", cores: []string{"FRA", "ITA"}, claims: []string{"GER"}},
+		{date: "1936.10.1", owner: "FRA", cores: []string{"FRA", "ITA"}},
+		{date: "1939.8.14", owner: "GER", cores: []string{"ITA"}},
+	}
+	for _, tt := range tests {
+		got := h.At(tt.date)
+		if got.Owner != tt.owner || !slices.Equal(got.Cores, tt.cores) || !slices.Equal(got.Claims, tt.claims) {
+			t.Errorf("At(%s) = owner %s, cores %v, claims %v; want %s, %v, %v", tt.date, got.Owner, got.Cores, got.Claims, tt.owner, tt.cores, tt.claims)
+		}
+	}
+	if len(h.Cores) != 1 {
+		t.Errorf("At modified the history: %v", h.Cores)
+	}
+}
+
+func TestStateValidate(t *testing.T) {
+	fp := filepath.Join(t.TempDir(), "4885-STATE_4885.txt")
+	err := os.WriteFile(fp, []byte(testStateSource), 0666)
+	if err != nil {
+		t.Fatal(err)
+	}
+	state, err := ParseState(fp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = state.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	state.History.VictoryPoints = append(state.History.VictoryPoints, VictoryPoint{Province: 6552, Value: 1.5})
+	state.History.Dated[0].VictoryPoints = []VictoryPoint{{Province: 1, Value: 3}}
+	if res := encodeState(t, state); !strings.Contains(res, "\t\t}\n\t\tvictory_points = { 6552 1.5 }\n") {
+		t.Fatalf("unexpected encode result:\n%s", res)
+	}
+	var vpErr *VictoryPointError
+	if err = state.Validate(); !errors.As(err, &vpErr) || vpErr.Province != 1 || vpErr.Date != "1939.1.1" || vpErr.StateID != 4885 {
+		t.Fatalf("expect victory point error, but got %v", err)
+	}
+}
+
+func encodeState(t *testing.T, state *State) string {
+	t.Helper()
+	data, err := state.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
 }
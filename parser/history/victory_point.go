@@ -0,0 +1,118 @@
+package history
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/kkkunny/TEW-hoi4/parser/pdx"
+)
+
+// VictoryPoint 胜利点，对应 victory_points = { 省份 分值 }
+type VictoryPoint struct {
+	Province int64   `json:"province"`
+	Value    float64 `json:"value"`
+}
+
+func (vp *VictoryPoint) UnmarshalPDX(value pdx.Value) error {
+	var pair []float64
+	err := pdx.UnmarshalNode(value, &pair)
+	if err != nil {
+		return err
+	}
+	if len(pair) != 2 {
+		return fmt.Errorf("%s: victory point expect 2 values, but got %d", value.Pos(), len(pair))
+	}
+	if pair[0] != float64(int64(pair[0])) {
+		return fmt.Errorf("%s: victory point province `%v` is not an integer", value.Pos(), pair[0])
+	}
+	vp.Province, vp.Value = int64(pair[0]), pair[1]
+	return nil
+}
+
+func (vp VictoryPoint) MarshalPDX() (pdx.Value, error) {
+	return pdx.NewList(pdx.NewInt(vp.Province), pdx.NewFloat(vp.Value)), nil
+}
+
+// VictoryPointError 胜利点所在的省份不属于该地区
+type VictoryPointError struct {
+	Filename string
+	Pos      pdx.Pos
+	StateID  int64
+	// Date 所在的带日期历史，为空表示开局时的历史
+	Date     string
+	Province int64
+}
+
+func (e *VictoryPointError) Error() string {
+	var prefix string
+	if e.Filename != "" {
+		prefix = e.Filename + ":"
+		if e.Pos.IsValid() {
+			prefix += e.Pos.String() + ":"
+		}
+		prefix += " "
+	}
+	if e.Date != "" {
+		return fmt.Sprintf("%sstate %d: victory point province %d at %s does not belong to the state", prefix, e.StateID, e.Province, e.Date)
+	}
+	return fmt.Sprintf("%sstate %d: victory point province %d does not belong to the state", prefix, e.StateID, e.Province)
+}
+
+// Validate 检查地区数据，所有错误通过errors.Join返回，可用errors.As取出*VictoryPointError
+func (state *State) Validate() error {
+	var errs []error
+	check := func(date string, history *StateHistory) {
+		for _, vp := range history.VictoryPoints {
+			if slices.Contains(state.Provinces, vp.Province) {
+				continue
+			}
+			err := &VictoryPointError{StateID: state.ID, Date: date, Province: vp.Province}
+			if state.file != nil {
+				err.Filename = state.file.Filename
+				err.Pos = state.victoryPointPos(date, vp.Province)
+			}
+			errs = append(errs, err)
+		}
+	}
+	check("", &state.History)
+	for _, dated := range state.History.Dated {
+		check(dated.Date, &dated.StateHistory)
+	}
+	return errors.Join(errs...)
+}
+
+// victoryPointPos 在语法树中查找胜利点的位置
+func (state *State) victoryPointPos(date string, province int64) pdx.Pos {
+	stateAssign := state.file.Body.Get("state")
+	if stateAssign == nil {
+		return pdx.Pos{}
+	}
+	stateBlock, ok := stateAssign.Value.(*pdx.Block)
+	if !ok {
+		return pdx.Pos{}
+	}
+	history := stateBlock.Body.Get("history")
+	if history == nil {
+		return pdx.Pos{}
+	}
+	if date != "" {
+		if block, ok := history.Value.(*pdx.Block); ok {
+			history = block.Body.Get(date)
+		}
+		if history == nil {
+			return pdx.Pos{}
+		}
+	}
+	block, ok := history.Value.(*pdx.Block)
+	if !ok {
+		return pdx.Pos{}
+	}
+	for _, a := range block.Body.GetAll("victory_points") {
+		var vp VictoryPoint
+		if pdx.UnmarshalNode(a, &vp) == nil && vp.Province == province {
+			return a.Pos()
+		}
+	}
+	return pdx.Pos{}
+}
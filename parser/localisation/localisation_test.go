@@ -1,20 +1,22 @@
 package localisation
 
 import (
-	"fmt"
 	"path/filepath"
 	"testing"
 
-	stlmaps "github.com/kkkunny/stl/container/maps"
-
-	"github.com/kkkunny/TEW-hoi4/config"
+	"github.com/kkkunny/TEW-hoi4/internal/testutil"
 )
 
 func TestParseLocalisation(t *testing.T) {
-	locs, err := ParseChineseLocalisationDir(filepath.Join(config.HOI4MyModPath, "TheEmptyWorld"))
+	locs, err := ParseLocalisation(filepath.Join(testutil.ModPath(), "localisation", "simp_chinese", "countries_l_simp_chinese.yml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	testutil.GoldenJSON(t, "countries_l_simp_chinese.json", locs)
+
+	allLocs, err := ParseLocalisationDir(testutil.ModPath())
 	if err != nil {
-		panic(err)
+		t.Fatal(err)
 	}
-	_, loc := stlmaps.Random(locs)
-	fmt.Printf(loc.Encode())
+	testutil.GoldenJSON(t, "localisation.json", allLocs)
 }
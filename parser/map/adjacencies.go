@@ -0,0 +1,339 @@
+package _map
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// AdjacencyType 额外相邻关系的类型，mod可以使用自定义的类型
+type AdjacencyType string
+
+const (
+	AdjacencyTypeLand       AdjacencyType = ""
+	AdjacencyTypeSea        AdjacencyType = "sea"
+	AdjacencyTypeImpassable AdjacencyType = "impassable"
+)
+
+// adjacenciesHeader adjacencies.csv的表头
+const adjacenciesHeader = "From;To;Type;Through;start_x;start_y;stop_x;stop_y;adjacency_rule_name;Comment"
+
+// isAdjacenciesHeader 是否为表头行，按内容判断而不要求位于第一行
+func isAdjacenciesHeader(line string) bool {
+	fields := strings.Split(line, ";")
+	if len(fields) < 3 {
+		return false
+	}
+	for i, name := range []string{"From", "To", "Type"} {
+		if !strings.EqualFold(strings.TrimSpace(fields[i]), name) {
+			return false
+		}
+	}
+	return true
+}
+
+// IsKnown 是否为游戏内置的相邻关系类型
+func (typ AdjacencyType) IsKnown() bool {
+	switch typ {
+	case AdjacencyTypeLand, AdjacencyTypeSea, AdjacencyTypeImpassable:
+		return true
+	default:
+		return false
+	}
+}
+
+// Adjacency adjacencies.csv中的一条额外相邻关系，如海峡
+type Adjacency struct {
+	From    int64         `json:"from"`
+	To      int64         `json:"to"`
+	Type    AdjacencyType `json:"type"`
+	Through int64         `json:"through"`
+	// StartX、StartY、StopX、StopY 地图上连线的起止坐标，-1表示使用省份中心
+	StartX   int64  `json:"start_x"`
+	StartY   int64  `json:"start_y"`
+	StopX    int64  `json:"stop_x"`
+	StopY    int64  `json:"stop_y"`
+	RuleName string `json:"rule_name,omitempty"`
+	Comment  string `json:"comment,omitempty"`
+	// Extra 第10列之后的列，原样保留
+	Extra []string `json:"extra,omitempty"`
+
+	line int
+	// raw、rawFields 读取时的原文及其对应的字段编码，字段未修改时按原文写回
+	raw       string
+	rawFields string
+}
+
+// NewStrait 创建经过海域through连接from和to的海峡
+func NewStrait(from, to, through int64, comment string) *Adjacency {
+	return &Adjacency{
+		From:    from,
+		To:      to,
+		Type:    AdjacencyTypeSea,
+		Through: through,
+		StartX:  -1,
+		StartY:  -1,
+		StopX:   -1,
+		StopY:   -1,
+		Comment: comment,
+	}
+}
+
+// Connects 是否使两个省份可以通行
+func (adj *Adjacency) Connects() bool {
+	return adj.Type != AdjacencyTypeImpassable
+}
+
+// Between 是否是两个省份之间（不分方向）的相邻关系
+func (adj *Adjacency) Between(a, b int64) bool {
+	return (adj.From == a && adj.To == b) || (adj.From == b && adj.To == a)
+}
+
+// Line 在adjacencies.csv中的行号，新增的为0
+func (adj *Adjacency) Line() int {
+	return adj.line
+}
+
+func (adj *Adjacency) encodeFields() string {
+	fields := []string{
+		strconv.FormatInt(adj.From, 10),
+		strconv.FormatInt(adj.To, 10),
+		string(adj.Type),
+		strconv.FormatInt(adj.Through, 10),
+		strconv.FormatInt(adj.StartX, 10),
+		strconv.FormatInt(adj.StartY, 10),
+		strconv.FormatInt(adj.StopX, 10),
+		strconv.FormatInt(adj.StopY, 10),
+		adj.RuleName,
+		adj.Comment,
+	}
+	return strings.Join(append(fields, adj.Extra...), ";")
+}
+
+// Encode 编码为adjacencies.csv中的一行，字段未修改时返回原文
+func (adj *Adjacency) Encode() string {
+	fields := adj.encodeFields()
+	if adj.raw != "" && fields == adj.rawFields {
+		return adj.raw
+	}
+	return fields
+}
+
+// adjacencyLine adjacencies.csv中的一行，表头、结束行、空行和注释只保留原文
+type adjacencyLine struct {
+	adjacency *Adjacency
+	raw       string
+	eol       string
+}
+
+// Adjacencies map/adjacencies.csv
+type Adjacencies struct {
+	path  string
+	bom   bool
+	lines []adjacencyLine
+}
+
+// ParseAdjacencies 读取map/adjacencies.csv
+func ParseAdjacencies(path string) (*Adjacencies, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	adjs := &Adjacencies{path: path}
+	if bytes.HasPrefix(data, []byte("\xEF\xBB\xBF")) {
+		adjs.bom = true
+		data = data[3:]
+	}
+	for i, text := range strings.SplitAfter(string(data), "\n") {
+		if text == "" {
+			continue
+		}
+		body := strings.TrimSuffix(text, "\n")
+		body = strings.TrimSuffix(body, "\r")
+		line := adjacencyLine{raw: body, eol: text[len(body):]}
+		trimmed := strings.TrimSpace(strings.TrimPrefix(body, "\uFEFF"))
+		if trimmed != "" && !strings.HasPrefix(trimmed, "#") && !strings.HasPrefix(trimmed, "-1;") && !isAdjacenciesHeader(trimmed) {
+			line.adjacency, err = parseAdjacency(body)
+			if err != nil {
+				return nil, fmt.Errorf("`%s`:%d: %s", path, i+1, err.Error())
+			}
+			line.adjacency.line = i + 1
+		}
+		adjs.lines = append(adjs.lines, line)
+	}
+	return adjs, nil
+}
+
+func parseAdjacency(text string) (*Adjacency, error) {
+	fields := strings.Split(text, ";")
+	if len(fields) < 4 {
+		return nil, fmt.Errorf("expect at least 4 columns, got %d", len(fields))
+	}
+	// 缺少的坐标列视为-1，缺少的规则名和注释视为空
+	for len(fields) < 10 {
+		if len(fields) < 8 {
+			fields = append(fields, "-1")
+		} else {
+			fields = append(fields, "")
+		}
+	}
+	var nums [7]int64
+	for i, j := range []int{0, 1, 3, 4, 5, 6, 7} {
+		v, err := strconv.ParseInt(strings.TrimSpace(fields[j]), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number `%s` in column %d", fields[j], j+1)
+		}
+		nums[i] = v
+	}
+	adj := &Adjacency{
+		From:     nums[0],
+		To:       nums[1],
+		Type:     AdjacencyType(fields[2]),
+		Through:  nums[2],
+		StartX:   nums[3],
+		StartY:   nums[4],
+		StopX:    nums[5],
+		StopY:    nums[6],
+		RuleName: fields[8],
+		Comment:  fields[9],
+		Extra:    fields[10:],
+		raw:      text,
+	}
+	if len(adj.Extra) == 0 {
+		adj.Extra = nil
+	}
+	adj.rawFields = adj.encodeFields()
+	return adj, nil
+}
+
+// Path 文件路径
+func (a *Adjacencies) Path() string {
+	return a.path
+}
+
+// List 按文件中的顺序返回所有相邻关系
+func (a *Adjacencies) List() []*Adjacency {
+	var adjs []*Adjacency
+	for _, line := range a.lines {
+		if line.adjacency != nil {
+			adjs = append(adjs, line.adjacency)
+		}
+	}
+	return adjs
+}
+
+// Add 添加相邻关系，添加在结束行（-1开头的行）之前
+func (a *Adjacencies) Add(adj *Adjacency) {
+	eol := a.lineEnding()
+	if len(a.lines) == 0 {
+		a.lines = append(a.lines, adjacencyLine{raw: adjacenciesHeader, eol: eol})
+	}
+	idx := len(a.lines)
+	for i := len(a.lines) - 1; i > 0; i-- {
+		if strings.HasPrefix(strings.TrimSpace(a.lines[i].raw), "-1;") && a.lines[i].adjacency == nil {
+			idx = i
+			break
+		}
+	}
+	if idx == len(a.lines) && a.lines[idx-1].eol == "" {
+		a.lines[idx-1].eol = eol
+	}
+	a.lines = append(a.lines[:idx], append([]adjacencyLine{{adjacency: adj, eol: eol}}, a.lines[idx:]...)...)
+}
+
+// AddStrait 添加经过海域through连接from和to的海峡，已存在两省份之间的海峡时只更新经过的海域
+func (a *Adjacencies) AddStrait(from, to, through int64, comment string) *Adjacency {
+	for _, adj := range a.List() {
+		if adj.Type == AdjacencyTypeSea && adj.Between(from, to) {
+			adj.Through = through
+			return adj
+		}
+	}
+	adj := NewStrait(from, to, through, comment)
+	a.Add(adj)
+	return adj
+}
+
+// RemoveStrait 删除from和to之间的海峡，返回删除的数量
+func (a *Adjacencies) RemoveStrait(from, to int64) int {
+	return a.RemoveFunc(func(adj *Adjacency) bool {
+		return adj.Type == AdjacencyTypeSea && adj.Between(from, to)
+	})
+}
+
+// RemoveFunc 删除满足条件的相邻关系，返回删除的数量
+func (a *Adjacencies) RemoveFunc(fn func(adj *Adjacency) bool) int {
+	var removed int
+	lines := a.lines[:0]
+	for _, line := range a.lines {
+		if line.adjacency != nil && fn(line.adjacency) {
+			removed++
+			continue
+		}
+		lines = append(lines, line)
+	}
+	a.lines = lines
+	return removed
+}
+
+// lineEnding 文件使用的换行符
+func (a *Adjacencies) lineEnding() string {
+	for _, line := range a.lines {
+		if line.eol != "" {
+			return line.eol
+		}
+	}
+	return "\n"
+}
+
+// Encode 编码为adjacencies.csv，未修改的行保持原样
+func (a *Adjacencies) Encode() string {
+	var buf strings.Builder
+	if a.bom {
+		buf.WriteString("\xEF\xBB\xBF")
+	}
+	for _, line := range a.lines {
+		if line.adjacency != nil {
+			buf.WriteString(line.adjacency.Encode())
+		} else {
+			buf.WriteString(line.raw)
+		}
+		buf.WriteString(line.eol)
+	}
+	return buf.String()
+}
+
+// Validate 检查引用的省份是否存在，以及海峡是否经过海洋省份，非内置的相邻关系类型只作为警告
+func (a *Adjacencies) Validate(defs map[int64]*ProvinceDef) (errs []error, warns []error) {
+	errorf := func(adj *Adjacency, format string, args ...any) {
+		errs = append(errs, fmt.Errorf("`%s`:%d: %s", a.path, adj.line, fmt.Sprintf(format, args...)))
+	}
+	for _, adj := range a.List() {
+		if !adj.Type.IsKnown() {
+			warns = append(warns, fmt.Errorf("`%s`:%d: unknown adjacency type `%s`", a.path, adj.line, adj.Type))
+		}
+		for _, id := range []int64{adj.From, adj.To} {
+			if _, ok := defs[id]; !ok {
+				errorf(adj, "unknown province %d", id)
+			}
+		}
+		if adj.From == adj.To {
+			errorf(adj, "adjacency from province %d to itself", adj.From)
+		}
+		if adj.Through == -1 {
+			if adj.Type == AdjacencyTypeSea {
+				errorf(adj, "sea adjacency between %d and %d has no through province", adj.From, adj.To)
+			}
+			continue
+		}
+		through, ok := defs[adj.Through]
+		if !ok {
+			errorf(adj, "unknown through province %d", adj.Through)
+		} else if adj.Type == AdjacencyTypeSea && through.Type != ProvinceTypeSea {
+			errorf(adj, "sea adjacency between %d and %d goes through %s province %d", adj.From, adj.To, through.Type, adj.Through)
+		}
+	}
+	return errs, warns
+}
@@ -0,0 +1,89 @@
+package _map
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kkkunny/TEW-hoi4/internal/testutil"
+)
+
+func TestParseAdjacencies(t *testing.T) {
+	path := filepath.Join(testutil.ModPath(), "map", "adjacencies.csv")
+	adjs, err := ParseAdjacencies(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testutil.GoldenJSON(t, "adjacencies.json", adjs.List())
+
+	src, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := adjs.Encode(); got != string(src) {
+		t.Fatalf("Encode is not lossless:\n%s", got)
+	}
+
+	definition, err := ParseDefinition(filepath.Join(testutil.ModPath(), "map", "definition.csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if errs, warns := adjs.Validate(definition.Map()); len(errs) != 0 || len(warns) != 0 {
+		t.Fatalf("unexpected errors %v, warnings %v", errs, warns)
+	}
+
+	if n := adjs.RemoveStrait(11467, 9851); n != 1 {
+		t.Fatalf("expect 1 strait removed, got %d", n)
+	}
+	adjs.AddStrait(3838, 11467, 11804, "Corsica-Rhine strait")
+	adjs.AddStrait(11467, 3838, 11804, "")
+	testutil.Golden(t, "adjacencies.csv", []byte(adjs.Encode()))
+}
+
+func TestAdjacenciesValidate(t *testing.T) {
+	fp := filepath.Join(t.TempDir(), "adjacencies.csv")
+	// 表头前的注释不影响表头的识别，自定义的类型只给出警告
+	src := "# adjacencies\n" + adjacenciesHeader + "\n3838;9851;sea;6488;-1;-1;-1;-1;;\n3838;1;;-1\n3838;9851;sea;-1;-1;-1;-1;-1;;\n3838;9851;canal;-1;-1;-1;-1;-1;;\n"
+	err := os.WriteFile(fp, []byte(src), 0666)
+	if err != nil {
+		t.Fatal(err)
+	}
+	adjs, err := ParseAdjacencies(fp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := adjs.Encode(); got != src {
+		t.Fatalf("Encode is not lossless:\n%q", got)
+	}
+	definition, err := ParseDefinition(filepath.Join(testutil.ModPath(), "map", "definition.csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if n := len(adjs.List()); n != 4 {
+		t.Fatalf("expect 4 adjacencies, got %d", n)
+	}
+	errs, warns := adjs.Validate(definition.Map())
+	if len(warns) != 1 || !strings.Contains(warns[0].Error(), ":6: unknown adjacency type `canal`") {
+		t.Fatalf("unexpected warnings %v", warns)
+	}
+	expects := []string{
+		":3: sea adjacency between 3838 and 9851 goes through land province 6488",
+		":4: unknown province 1",
+		":5: sea adjacency between 3838 and 9851 has no through province",
+	}
+	if len(errs) != len(expects) {
+		t.Fatalf("expect %d errors, got %v", len(expects), errs)
+	}
+	for i, expect := range expects {
+		if !strings.Contains(errs[i].Error(), expect) {
+			t.Errorf("expect error containing `%s`, got `%s`", expect, errs[i].Error())
+		}
+	}
+
+	adjs.Add(NewStrait(9851, 11467, 11804, ""))
+	if got := adjs.Encode(); got != src+"9851;11467;sea;11804;-1;-1;-1;-1;;\n" {
+		t.Fatalf("unexpected encode after add:\n%q", got)
+	}
+}
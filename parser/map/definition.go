@@ -1,70 +1,241 @@
 package _map
 
 import (
+	"bytes"
 	"fmt"
-	"io"
 	"os"
-	"regexp"
 	"strconv"
-
-	stlslices "github.com/kkkunny/stl/container/slices"
+	"strings"
 )
 
-type StateType string
+// ProvinceType 省份类型
+type ProvinceType string
 
 const (
-	StateTypeLand StateType = "land"
-	StateTypeSea  StateType = "sea"
+	ProvinceTypeLand ProvinceType = "land"
+	ProvinceTypeSea  ProvinceType = "sea"
+	ProvinceTypeLake ProvinceType = "lake"
 )
 
-type StateDef struct {
-	ID          int64     `json:"id"`
-	StateType   StateType `json:"state_type"`
-	Landform    string    `json:"landform"`
-	ContinentID int64     `json:"continent_id"`
+// ProvinceDef map/definition.csv中的一个省份
+type ProvinceDef struct {
+	ID int64 `json:"id"`
+	// Color 省份在provinces.bmp中的颜色
+	Color     [3]uint8     `json:"color"`
+	Type      ProvinceType `json:"type"`
+	Coastal   bool         `json:"coastal"`
+	Terrain   string       `json:"terrain"`
+	Continent int64        `json:"continent"`
+	// Extra 第8列之后的列，原样保留
+	Extra []string `json:"extra,omitempty"`
+
+	line int
+	// raw、rawFields 读取时的原文及其对应的字段编码，字段未修改时按原文写回
+	raw       string
+	rawFields string
 }
 
-func ParseStateDef(path string) (map[int64]*StateDef, error) {
-	file, err := os.Open(path)
-	if err != nil {
-		return nil, err
+// Line 省份在definition.csv中的行号，新增的省份为0
+func (def *ProvinceDef) Line() int {
+	return def.line
+}
+
+func (def *ProvinceDef) encodeFields() string {
+	fields := []string{
+		strconv.FormatInt(def.ID, 10),
+		strconv.FormatUint(uint64(def.Color[0]), 10),
+		strconv.FormatUint(uint64(def.Color[1]), 10),
+		strconv.FormatUint(uint64(def.Color[2]), 10),
+		string(def.Type),
+		strconv.FormatBool(def.Coastal),
+		def.Terrain,
+		strconv.FormatInt(def.Continent, 10),
+	}
+	return strings.Join(append(fields, def.Extra...), ";")
+}
+
+// Encode 编码为definition.csv中的一行，字段未修改时返回原文
+func (def *ProvinceDef) Encode() string {
+	fields := def.encodeFields()
+	if def.raw != "" && fields == def.rawFields {
+		return def.raw
 	}
-	defer file.Close()
+	return fields
+}
+
+// definitionLine definition.csv中的一行，不是省份的行（空行、注释）只保留原文
+type definitionLine struct {
+	province *ProvinceDef
+	raw      string
+	eol      string
+}
+
+// Definition map/definition.csv
+type Definition struct {
+	path  string
+	bom   bool
+	lines []definitionLine
+}
 
-	data, err := io.ReadAll(file)
+// ParseDefinition 读取map/definition.csv
+func ParseDefinition(path string) (*Definition, error) {
+	data, err := os.ReadFile(path)
 	if err != nil {
 		return nil, err
 	}
-	matches := regexp.MustCompile(`(\d+);(\d+);(\d+);(\d+);(sea|land);(false|true);(\w+);(\d+)`).FindAllStringSubmatch(string(data), -1)
-	stateDefs, err := stlslices.MapError(matches, func(_ int, match []string) (*StateDef, error) {
-		id, err := strconv.ParseInt(match[1], 10, 64)
-		if err != nil {
-			return nil, err
+	def := &Definition{path: path}
+	if bytes.HasPrefix(data, []byte("\xEF\xBB\xBF")) {
+		def.bom = true
+		data = data[3:]
+	}
+	for i, text := range strings.SplitAfter(string(data), "\n") {
+		if text == "" {
+			continue
 		}
-		switch match[5] {
-		case "land", "sea":
-		default:
-			return nil, fmt.Errorf("unknown state type `%s`", match[5])
+		body := strings.TrimSuffix(text, "\n")
+		body = strings.TrimSuffix(body, "\r")
+		line := definitionLine{raw: body, eol: text[len(body):]}
+		trimmed := strings.TrimSpace(body)
+		if trimmed != "" && !strings.HasPrefix(trimmed, "#") {
+			line.province, err = parseProvinceDef(body)
+			if err != nil {
+				return nil, fmt.Errorf("`%s`:%d: %s", path, i+1, err.Error())
+			}
+			line.province.line = i + 1
 		}
-		continentID, err := strconv.ParseInt(match[8], 10, 64)
+		def.lines = append(def.lines, line)
+	}
+	return def, nil
+}
+
+func parseProvinceDef(text string) (*ProvinceDef, error) {
+	fields := strings.Split(text, ";")
+	if len(fields) < 8 {
+		return nil, fmt.Errorf("expect at least 8 columns, got %d", len(fields))
+	}
+	id, err := strconv.ParseInt(fields[0], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid province id `%s`", fields[0])
+	}
+	var clr [3]uint8
+	for i := range clr {
+		v, err := strconv.ParseUint(fields[1+i], 10, 8)
 		if err != nil {
-			return nil, err
+			return nil, fmt.Errorf("province %d: invalid color component `%s`", id, fields[1+i])
 		}
-		return &StateDef{
-			ID:          id,
-			StateType:   StateType(match[5]),
-			Landform:    match[7],
-			ContinentID: continentID,
-		}, nil
-	})
+		clr[i] = uint8(v)
+	}
+	typ := ProvinceType(fields[4])
+	switch typ {
+	case ProvinceTypeLand, ProvinceTypeSea, ProvinceTypeLake:
+	default:
+		return nil, fmt.Errorf("province %d: unknown province type `%s`", id, fields[4])
+	}
+	coastal, err := strconv.ParseBool(fields[5])
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("province %d: invalid coastal `%s`", id, fields[5])
+	}
+	continent, err := strconv.ParseInt(fields[7], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("province %d: invalid continent `%s`", id, fields[7])
 	}
-	return stlslices.ToMap[*StateDef, []*StateDef, int64, *StateDef, map[int64]*StateDef](stateDefs, func(def *StateDef) (int64, *StateDef) {
-		return def.ID, def
-	}), nil
+	def := &ProvinceDef{
+		ID:        id,
+		Color:     clr,
+		Type:      typ,
+		Coastal:   coastal,
+		Terrain:   fields[6],
+		Continent: continent,
+		Extra:     fields[8:],
+		raw:       text,
+	}
+	if len(def.Extra) == 0 {
+		def.Extra = nil
+	}
+	def.rawFields = def.encodeFields()
+	return def, nil
+}
+
+// Path 文件路径
+func (d *Definition) Path() string {
+	return d.path
+}
+
+// Provinces 按文件中的顺序返回所有省份，包括第一行的0号省份
+func (d *Definition) Provinces() []*ProvinceDef {
+	var provinces []*ProvinceDef
+	for _, line := range d.lines {
+		if line.province != nil {
+			provinces = append(provinces, line.province)
+		}
+	}
+	return provinces
+}
+
+// Map 返回以省份id为键的省份，id重复时保留后出现的省份
+func (d *Definition) Map() map[int64]*ProvinceDef {
+	provinces := make(map[int64]*ProvinceDef, len(d.lines))
+	for _, p := range d.Provinces() {
+		provinces[p.ID] = p
+	}
+	return provinces
 }
 
-func (def *StateDef) Encode() string {
-	return fmt.Sprintf("%d;%d;%d;%d;%s;%s;%s;%d", def.ID, 0, 0, 0, def.StateType, "false", def.Landform, def.ContinentID)
+// Add 在文件末尾添加省份
+func (d *Definition) Add(def *ProvinceDef) {
+	eol := "\n"
+	if n := len(d.lines); n != 0 {
+		if d.lines[n-1].eol == "" {
+			d.lines[n-1].eol = d.lineEnding()
+		}
+		eol = d.lineEnding()
+	}
+	d.lines = append(d.lines, definitionLine{province: def, eol: eol})
+}
+
+// lineEnding 文件使用的换行符
+func (d *Definition) lineEnding() string {
+	for _, line := range d.lines {
+		if line.eol != "" {
+			return line.eol
+		}
+	}
+	return "\n"
+}
+
+// Encode 编码为definition.csv，未修改的行保持原样
+func (d *Definition) Encode() string {
+	var buf strings.Builder
+	if d.bom {
+		buf.WriteString("\xEF\xBB\xBF")
+	}
+	for _, line := range d.lines {
+		if line.province != nil {
+			buf.WriteString(line.province.Encode())
+		} else {
+			buf.WriteString(line.raw)
+		}
+		buf.WriteString(line.eol)
+	}
+	return buf.String()
+}
+
+// Validate 检查重复的省份id与颜色
+func (d *Definition) Validate() []error {
+	var errs []error
+	ids := make(map[int64]*ProvinceDef)
+	colors := make(map[[3]uint8]*ProvinceDef)
+	for _, p := range d.Provinces() {
+		if prev, ok := ids[p.ID]; ok {
+			errs = append(errs, fmt.Errorf("`%s`:%d: duplicate province id %d, previous at line %d", d.path, p.line, p.ID, prev.line))
+		} else {
+			ids[p.ID] = p
+		}
+		if prev, ok := colors[p.Color]; ok {
+			errs = append(errs, fmt.Errorf("`%s`:%d: province %d has the same color rgb { %d %d %d } as province %d at line %d", d.path, p.line, p.ID, p.Color[0], p.Color[1], p.Color[2], prev.ID, prev.line))
+		} else {
+			colors[p.Color] = p
+		}
+	}
+	return errs
 }
@@ -1,19 +1,81 @@
 package _map
 
 import (
-	"fmt"
+	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
-	"github.com/kkkunny/TEW-hoi4/config"
+	"github.com/kkkunny/TEW-hoi4/internal/testutil"
 )
 
 func TestParseDefinition(t *testing.T) {
-	defs, err := ParseStateDef(filepath.Join(config.TEWRootPath, "map", "definition.csv"))
+	path := filepath.Join(testutil.ModPath(), "map", "definition.csv")
+	definition, err := ParseDefinition(path)
 	if err != nil {
-		panic(err)
+		t.Fatal(err)
 	}
-	for _, def := range defs {
-		fmt.Println(def.Encode())
+	testutil.GoldenJSON(t, "definition.json", definition.Provinces())
+
+	src, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := definition.Encode(); got != string(src) {
+		t.Fatalf("Encode is not lossless:\n%s", got)
+	}
+	if errs := definition.Validate(); len(errs) != 0 {
+		t.Fatalf("unexpected errors %v", errs)
+	}
+
+	provinces := definition.Map()
+	provinces[9851].Coastal = true
+	provinces[11804].Type = ProvinceTypeLake
+	definition.Add(&ProvinceDef{ID: 11805, Color: [3]uint8{1, 2, 3}, Type: ProvinceTypeSea, Terrain: "ocean"})
+	testutil.Golden(t, "definition.csv", []byte(definition.Encode()))
+}
+
+const testDefinitionSource = "0;0;0;0;land;false;unknown;0\r\n# comment\r\n1;10;20;30;land;true;forest;2;extra\r\n\r\n2;10;20;30;lake;false;lakes;0\r\n1;40;50;60;sea;false;ocean;0"
+
+func TestDefinitionValidate(t *testing.T) {
+	fp := filepath.Join(t.TempDir(), "definition.csv")
+	err := os.WriteFile(fp, []byte(testDefinitionSource), 0666)
+	if err != nil {
+		t.Fatal(err)
+	}
+	definition, err := ParseDefinition(fp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := definition.Encode(); got != testDefinitionSource {
+		t.Fatalf("Encode is not lossless:\n%q", got)
+	}
+	if provinces := definition.Provinces(); len(provinces) != 4 || provinces[1].Extra[0] != "extra" || provinces[1].Line() != 3 {
+		t.Fatalf("unexpected provinces %+v", provinces)
+	}
+
+	errs := definition.Validate()
+	if len(errs) != 2 {
+		t.Fatalf("expect 2 errors, got %v", errs)
+	}
+	if !strings.Contains(errs[0].Error(), ":5: province 2 has the same color") || !strings.Contains(errs[1].Error(), ":6: duplicate province id 1, previous at line 3") {
+		t.Fatalf("unexpected errors %v", errs)
+	}
+
+	definition.Add(&ProvinceDef{ID: 3, Color: [3]uint8{7, 8, 9}, Type: ProvinceTypeLand, Terrain: "plains", Continent: 1})
+	if got := definition.Encode(); got != testDefinitionSource+"\r\n3;7;8;9;land;false;plains;1\r\n" {
+		t.Fatalf("unexpected encode after add:\n%q", got)
+	}
+}
+
+func TestParseDefinitionError(t *testing.T) {
+	fp := filepath.Join(t.TempDir(), "definition.csv")
+	err := os.WriteFile(fp, []byte("0;0;0;0;land;false;unknown;0\n1;1;1;1;swamp;false;marsh;1\n"), 0666)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = ParseDefinition(fp)
+	if err == nil || !strings.Contains(err.Error(), ":2: province 1: unknown province type `swamp`") {
+		t.Fatalf("unexpected error %v", err)
 	}
 }
@@ -0,0 +1,174 @@
+package _map
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"golang.org/x/exp/maps"
+
+	"github.com/kkkunny/TEW-hoi4/parser/history"
+)
+
+// MapLinks 省份、地区、战略区域与补给区域之间的从属关系
+type MapLinks struct {
+	Provinces        map[int64]*ProvinceDef
+	States           []*history.State
+	StrategicRegions []*StrategicRegion
+	SupplyAreas      []*SupplyArea
+
+	provinceStates  map[int64][]*history.State
+	provinceRegions map[int64][]*StrategicRegion
+	stateAreas      map[int64][]*SupplyArea
+	states          map[int64]*history.State
+}
+
+// LinkMap 建立省份、地区、战略区域与补给区域之间的从属关系，supplyAreas可以为空
+func LinkMap(provinces map[int64]*ProvinceDef, states []*history.State, regions []*StrategicRegion, supplyAreas []*SupplyArea) *MapLinks {
+	l := &MapLinks{
+		Provinces:        provinces,
+		States:           states,
+		StrategicRegions: regions,
+		SupplyAreas:      supplyAreas,
+		provinceStates:   make(map[int64][]*history.State),
+		provinceRegions:  make(map[int64][]*StrategicRegion),
+		stateAreas:       make(map[int64][]*SupplyArea),
+		states:           make(map[int64]*history.State, len(states)),
+	}
+	for _, state := range states {
+		l.states[state.ID] = state
+		for _, p := range state.Provinces {
+			if !slices.Contains(l.provinceStates[p], state) {
+				l.provinceStates[p] = append(l.provinceStates[p], state)
+			}
+		}
+	}
+	for _, region := range regions {
+		for _, p := range region.Provinces {
+			if !slices.Contains(l.provinceRegions[p], region) {
+				l.provinceRegions[p] = append(l.provinceRegions[p], region)
+			}
+		}
+	}
+	for _, area := range supplyAreas {
+		for _, s := range area.States {
+			if !slices.Contains(l.stateAreas[s], area) {
+				l.stateAreas[s] = append(l.stateAreas[s], area)
+			}
+		}
+	}
+	return l
+}
+
+// ProvinceState 返回省份所属的地区，省份不属于任何地区时返回false
+func (l *MapLinks) ProvinceState(province int64) (*history.State, bool) {
+	states := l.provinceStates[province]
+	if len(states) == 0 {
+		return nil, false
+	}
+	return states[0], true
+}
+
+// ProvinceStrategicRegion 返回省份所属的战略区域，省份不属于任何战略区域时返回false
+func (l *MapLinks) ProvinceStrategicRegion(province int64) (*StrategicRegion, bool) {
+	regions := l.provinceRegions[province]
+	if len(regions) == 0 {
+		return nil, false
+	}
+	return regions[0], true
+}
+
+// StateStrategicRegions 返回地区的省份所属的战略区域
+func (l *MapLinks) StateStrategicRegions(state *history.State) []*StrategicRegion {
+	var regions []*StrategicRegion
+	for _, p := range state.Provinces {
+		for _, region := range l.provinceRegions[p] {
+			if !slices.Contains(regions, region) {
+				regions = append(regions, region)
+			}
+		}
+	}
+	return regions
+}
+
+// StateSupplyArea 返回地区所属的补给区域，地区不属于任何补给区域时返回false
+func (l *MapLinks) StateSupplyArea(stateID int64) (*SupplyArea, bool) {
+	areas := l.stateAreas[stateID]
+	if len(areas) == 0 {
+		return nil, false
+	}
+	return areas[0], true
+}
+
+// Validate 检查每个陆地省份是否恰好属于一个地区，每个省份是否恰好属于一个战略区域，
+// 每个地区是否只属于一个战略区域，以及引用的省份和地区是否存在；0号省份不参与检查
+func (l *MapLinks) Validate() []error {
+	var errs []error
+	errorf := func(path string, format string, args ...any) {
+		msg := fmt.Sprintf(format, args...)
+		if path != "" {
+			msg = fmt.Sprintf("`%s`: %s", path, msg)
+		}
+		errs = append(errs, errors.New(msg))
+	}
+
+	for _, state := range l.States {
+		for _, p := range state.Provinces {
+			if _, ok := l.Provinces[p]; !ok {
+				errorf(state.Path(), "state %d: unknown province %d", state.ID, p)
+			}
+		}
+	}
+	for _, region := range l.StrategicRegions {
+		for _, p := range region.Provinces {
+			if _, ok := l.Provinces[p]; !ok {
+				errorf(region.Path(), "strategic region %d: unknown province %d", region.ID, p)
+			}
+		}
+	}
+	for _, area := range l.SupplyAreas {
+		for _, s := range area.States {
+			if _, ok := l.states[s]; !ok {
+				errorf(area.Path(), "supply area %d: unknown state %d", area.ID, s)
+			}
+		}
+	}
+
+	ids := maps.Keys(l.Provinces)
+	slices.Sort(ids)
+	for _, id := range ids {
+		if id == 0 {
+			continue
+		}
+		if l.Provinces[id].Type == ProvinceTypeLand {
+			switch states := l.provinceStates[id]; {
+			case len(states) == 0:
+				errorf("", "land province %d is not in any state", id)
+			case len(states) > 1:
+				errorf(states[1].Path(), "province %d is in multiple states: %s", id, joinIDs(states, func(s *history.State) int64 { return s.ID }))
+			}
+		}
+		switch regions := l.provinceRegions[id]; {
+		case len(regions) == 0:
+			errorf("", "province %d is not in any strategic region", id)
+		case len(regions) > 1:
+			errorf(regions[1].Path(), "province %d is in multiple strategic regions: %s", id, joinIDs(regions, func(r *StrategicRegion) int64 { return r.ID }))
+		}
+	}
+
+	for _, state := range l.States {
+		if regions := l.StateStrategicRegions(state); len(regions) > 1 {
+			errorf(state.Path(), "state %d spans multiple strategic regions: %s", state.ID, joinIDs(regions, func(r *StrategicRegion) int64 { return r.ID }))
+		}
+	}
+	return errs
+}
+
+func joinIDs[T any](list []T, id func(T) int64) string {
+	ids := make([]string, len(list))
+	for i, e := range list {
+		ids[i] = fmt.Sprint(id(e))
+	}
+	return strings.Join(ids, ", ")
+}
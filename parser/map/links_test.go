@@ -0,0 +1,72 @@
+package _map
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kkkunny/TEW-hoi4/internal/testutil"
+	"github.com/kkkunny/TEW-hoi4/parser/history"
+)
+
+func TestLinkMap(t *testing.T) {
+	modPath := testutil.ModPath()
+	definition, err := ParseDefinition(filepath.Join(modPath, "map", "definition.csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	states, err := history.ParseStateDir(modPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	regions, err := ParseStrategicRegionDir(modPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	areas, err := ParseSupplyAreaDir(modPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	links := LinkMap(definition.Map(), states, regions, areas)
+	if errs := links.Validate(); len(errs) != 0 {
+		t.Fatalf("unexpected errors %v", errs)
+	}
+	if state, ok := links.ProvinceState(9851); !ok || state.ID != 1 {
+		t.Fatalf("expect province 9851 in state 1, got %v", state)
+	}
+	if region, ok := links.ProvinceStrategicRegion(6488); !ok || region.ID != 2 {
+		t.Fatalf("expect province 6488 in strategic region 2, got %v", region)
+	}
+	if area, ok := links.StateSupplyArea(2); !ok || area.ID != 1 {
+		t.Fatalf("expect state 2 in supply area 1, got %v", area)
+	}
+
+	states = append(states,
+		&history.State{ID: 3, Provinces: []int64{9851, 6488, 12345}},
+	)
+	states[0].Provinces = states[0].Provinces[1:]
+	regions[0].Provinces = regions[0].Provinces[1:]
+	regions[1].Provinces = append(regions[1].Provinces, 9851)
+	areas[0].States = append(areas[0].States, 4)
+	errs := LinkMap(definition.Map(), states, regions, areas).Validate()
+	expects := []string{
+		"state 3: unknown province 12345",
+		"supply area 1: unknown state 4",
+		"land province 3838 is not in any state",
+		"province 3838 is not in any strategic region",
+		"province 6488 is in multiple states: 2, 3",
+		"province 9851 is in multiple states: 1, 3",
+		"2-Rhineland.txt`: province 9851 is in multiple strategic regions: 1, 2",
+		"1-Corsica.txt`: state 1 spans multiple strategic regions: 1, 2",
+		"state 3 spans multiple strategic regions: 1, 2",
+	}
+	if len(errs) != len(expects) {
+		t.Fatalf("expect %d errors, got %v", len(expects), errs)
+	}
+	for i, expect := range expects {
+		if !strings.Contains(errs[i].Error(), expect) {
+			t.Errorf("expect error containing `%s`, got `%s`", expect, errs[i].Error())
+		}
+	}
+}
@@ -0,0 +1,181 @@
+package _map
+
+import (
+	"cmp"
+	"fmt"
+	"image"
+	"os"
+	"slices"
+
+	"golang.org/x/image/bmp"
+)
+
+// ProvinceStats 省份在provinces.bmp中的范围，坐标以图片左上角为原点
+type ProvinceStats struct {
+	ID int64 `json:"id"`
+	// Pixels 像素数
+	Pixels int `json:"pixels"`
+	// Bounds 包含省份所有像素的最小矩形
+	Bounds image.Rectangle `json:"bounds"`
+
+	sumX, sumY int64
+}
+
+// Centroid 省份所有像素的平均位置
+func (s *ProvinceStats) Centroid() (x, y float64) {
+	if s.Pixels == 0 {
+		return 0, 0
+	}
+	// 像素的中心在其坐标加0.5处
+	return float64(s.sumX)/float64(s.Pixels) + 0.5, float64(s.sumY)/float64(s.Pixels) + 0.5
+}
+
+// ProvinceMap provinces.bmp中每个像素所属的省份
+type ProvinceMap struct {
+	width, height int
+	// pixels 每个像素所属省份在provinces中的下标
+	pixels    []int32
+	provinces []*ProvinceStats
+	index     map[int64]int32
+}
+
+// ParseProvinceMap 读取provinces.bmp，按definition.csv中的颜色确定每个像素所属的省份
+func ParseProvinceMap(path string, defs map[int64]*ProvinceDef) (*ProvinceMap, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	img, err := bmp.Decode(file)
+	if err != nil {
+		return nil, fmt.Errorf("`%s` decode error: %s", path, err.Error())
+	}
+
+	color2ID := make(map[[3]uint8]int64, len(defs))
+	for _, def := range defs {
+		color2ID[def.Color] = def.ID
+	}
+	bounds := img.Bounds()
+	m := &ProvinceMap{
+		width:  bounds.Dx(),
+		height: bounds.Dy(),
+		pixels: make([]int32, bounds.Dx()*bounds.Dy()),
+		index:  make(map[int64]int32),
+	}
+	rgba, _ := img.(*image.RGBA)
+	var lastColor [3]uint8
+	lastIndex := int32(-1)
+	for y := 0; y < m.height; y++ {
+		for x := 0; x < m.width; x++ {
+			var clr [3]uint8
+			if rgba != nil {
+				offset := rgba.PixOffset(bounds.Min.X+x, bounds.Min.Y+y)
+				clr = [3]uint8{rgba.Pix[offset], rgba.Pix[offset+1], rgba.Pix[offset+2]}
+			} else {
+				r, g, b, _ := img.At(bounds.Min.X+x, bounds.Min.Y+y).RGBA()
+				clr = [3]uint8{uint8(r >> 8), uint8(g >> 8), uint8(b >> 8)}
+			}
+
+			// 相邻像素大多属于同一省份，缓存上一个像素的结果
+			if lastIndex < 0 || clr != lastColor {
+				id, ok := color2ID[clr]
+				if !ok {
+					return nil, fmt.Errorf("`%s`: pixel %s has color rgb { %d %d %d } not in definition.csv", path, image.Pt(x, y), clr[0], clr[1], clr[2])
+				}
+				idx, ok := m.index[id]
+				if !ok {
+					idx = int32(len(m.provinces))
+					m.index[id] = idx
+					m.provinces = append(m.provinces, &ProvinceStats{ID: id, Bounds: image.Rect(x, y, x+1, y+1)})
+				}
+				lastColor, lastIndex = clr, idx
+			}
+
+			m.pixels[y*m.width+x] = lastIndex
+			stats := m.provinces[lastIndex]
+			stats.Pixels++
+			stats.sumX += int64(x)
+			stats.sumY += int64(y)
+			stats.Bounds = stats.Bounds.Union(image.Rect(x, y, x+1, y+1))
+		}
+	}
+	return m, nil
+}
+
+// Size 图片的宽和高
+func (m *ProvinceMap) Size() (width, height int) {
+	return m.width, m.height
+}
+
+// At 返回像素所属的省份，超出图片范围时返回false
+func (m *ProvinceMap) At(x, y int) (int64, bool) {
+	if x < 0 || y < 0 || x >= m.width || y >= m.height {
+		return 0, false
+	}
+	return m.provinces[m.pixels[y*m.width+x]].ID, true
+}
+
+// Provinces 按id顺序返回图上出现的所有省份
+func (m *ProvinceMap) Provinces() []*ProvinceStats {
+	provinces := slices.Clone(m.provinces)
+	slices.SortFunc(provinces, func(l, r *ProvinceStats) int {
+		return cmp.Compare(l.ID, r.ID)
+	})
+	return provinces
+}
+
+// Province 返回省份在图上的统计信息，省份不在图上时返回false
+func (m *ProvinceMap) Province(id int64) (*ProvinceStats, bool) {
+	idx, ok := m.index[id]
+	if !ok {
+		return nil, false
+	}
+	return m.provinces[idx], true
+}
+
+// Neighbors 返回在图上直接相接（上下左右）的省份，adjs中的海峡等额外相邻关系会加入，
+// 不可通行的关系会去掉两个省份的相邻；每个省份的相邻省份按id排序
+func (m *ProvinceMap) Neighbors(adjs ...*Adjacency) map[int64][]int64 {
+	pairs := make(map[[2]int64]struct{})
+	pairKey := func(a, b int64) [2]int64 {
+		if a > b {
+			a, b = b, a
+		}
+		return [2]int64{a, b}
+	}
+	for y := 0; y < m.height; y++ {
+		for x := 0; x < m.width; x++ {
+			idx := m.pixels[y*m.width+x]
+			if x+1 < m.width {
+				if other := m.pixels[y*m.width+x+1]; other != idx {
+					pairs[pairKey(m.provinces[idx].ID, m.provinces[other].ID)] = struct{}{}
+				}
+			}
+			if y+1 < m.height {
+				if other := m.pixels[(y+1)*m.width+x]; other != idx {
+					pairs[pairKey(m.provinces[idx].ID, m.provinces[other].ID)] = struct{}{}
+				}
+			}
+		}
+	}
+	for _, adj := range adjs {
+		if adj.From == adj.To {
+			continue
+		}
+		if adj.Connects() {
+			pairs[pairKey(adj.From, adj.To)] = struct{}{}
+		} else {
+			delete(pairs, pairKey(adj.From, adj.To))
+		}
+	}
+
+	neighbors := make(map[int64][]int64)
+	for pair := range pairs {
+		neighbors[pair[0]] = append(neighbors[pair[0]], pair[1])
+		neighbors[pair[1]] = append(neighbors[pair[1]], pair[0])
+	}
+	for _, ns := range neighbors {
+		slices.Sort(ns)
+	}
+	return neighbors
+}
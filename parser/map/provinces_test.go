@@ -0,0 +1,45 @@
+package _map
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/kkkunny/TEW-hoi4/internal/testutil"
+)
+
+func TestParseProvinceMap(t *testing.T) {
+	definition, err := ParseDefinition(filepath.Join(testutil.ModPath(), "map", "definition.csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := ParseProvinceMap(filepath.Join(testutil.ModPath(), "map", "provinces.bmp"), definition.Map())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type provinceInfo struct {
+		*ProvinceStats
+		Centroid [2]float64 `json:"centroid"`
+	}
+	var provinces []provinceInfo
+	for _, p := range m.Provinces() {
+		x, y := p.Centroid()
+		provinces = append(provinces, provinceInfo{ProvinceStats: p, Centroid: [2]float64{x, y}})
+	}
+	testutil.GoldenJSON(t, "provinces.json", provinces)
+
+	if id, ok := m.At(0, 0); !ok || id != 3838 {
+		t.Fatalf("expect province 3838 at (0, 0), got %d", id)
+	}
+	if _, ok := m.At(6, 0); ok {
+		t.Fatal("expect no province outside the map")
+	}
+
+	testutil.GoldenJSON(t, "neighbors.json", m.Neighbors())
+
+	adjs, err := ParseAdjacencies(filepath.Join(testutil.ModPath(), "map", "adjacencies.csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	testutil.GoldenJSON(t, "neighbors_adjacencies.json", m.Neighbors(adjs.List()...))
+}
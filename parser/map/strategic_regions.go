@@ -0,0 +1,76 @@
+package _map
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	stlslices "github.com/kkkunny/stl/container/slices"
+
+	"github.com/kkkunny/TEW-hoi4/parser/pdx"
+)
+
+// StrategicRegion map/strategicregions中的战略区域
+type StrategicRegion struct {
+	ID           int64   `json:"id" pdx:"id"`
+	Name         string  `json:"name,omitempty" pdx:"name,quoted"`
+	Provinces    []int64 `json:"provinces" pdx:"provinces"`
+	NavalTerrain string  `json:"naval_terrain,omitempty" pdx:"naval_terrain,omitempty"`
+	// Rest 其他未建模的语句，如天气
+	Rest pdx.Statements `json:"-" pdx:",remain"`
+
+	// file 读取时的语法树
+	file *pdx.File
+}
+
+func ParseStrategicRegion(path string) (*StrategicRegion, error) {
+	file, err := pdx.ParseFile(path)
+	if err != nil {
+		return nil, err
+	}
+	a := file.Body.Get("strategic_region")
+	if a == nil {
+		return nil, errors.New("missing `strategic_region`")
+	}
+	var region StrategicRegion
+	err = pdx.UnmarshalNode(a, &region)
+	if err != nil {
+		return nil, err
+	}
+	region.file = file
+	return &region, nil
+}
+
+// Path 返回战略区域文件路径，不是由ParseStrategicRegion得到时返回空字符串
+func (region *StrategicRegion) Path() string {
+	if region.file == nil {
+		return ""
+	}
+	return region.file.Filename
+}
+
+// Encode 编码为战略区域文件，由ParseStrategicRegion得到的战略区域只会修改发生变化的语句
+func (region *StrategicRegion) Encode() ([]byte, error) {
+	return pdx.EncodeFile(region.file, &struct {
+		Region *StrategicRegion `pdx:"strategic_region"`
+	}{Region: region})
+}
+
+func ParseStrategicRegionDir(modPath string) ([]*StrategicRegion, error) {
+	dir := filepath.Join(modPath, "map", "strategicregions")
+	regionInfos, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	return stlslices.FlatMapError(regionInfos, func(_ int, e os.DirEntry) ([]*StrategicRegion, error) {
+		if e.IsDir() || filepath.Ext(e.Name()) != ".txt" {
+			return nil, nil
+		}
+		region, err := ParseStrategicRegion(filepath.Join(dir, e.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("strategic region %s parse error: %s", e.Name(), err.Error())
+		}
+		return []*StrategicRegion{region}, nil
+	})
+}
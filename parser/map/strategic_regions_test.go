@@ -0,0 +1,67 @@
+package _map
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kkkunny/TEW-hoi4/internal/testutil"
+)
+
+func TestParseStrategicRegion(t *testing.T) {
+	regions, err := ParseStrategicRegionDir(testutil.ModPath())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(regions) != 2 {
+		t.Fatalf("expect 2 strategic regions, got %d", len(regions))
+	}
+	testutil.GoldenJSON(t, "strategic_regions.json", regions)
+
+	for _, region := range regions {
+		src, err := os.ReadFile(region.Path())
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := region.Encode()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != string(src) {
+			t.Errorf("%s: Encode is not lossless:\n%s", region.Path(), got)
+		}
+	}
+	regions[0].Provinces = append(regions[0].Provinces, 6488)
+	got, err := regions[0].Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	testutil.Golden(t, filepath.Base(regions[0].Path()), got)
+}
+
+func TestParseSupplyArea(t *testing.T) {
+	areas, err := ParseSupplyAreaDir(testutil.ModPath())
+	if err != nil {
+		t.Fatal(err)
+	}
+	testutil.GoldenJSON(t, "supply_areas.json", areas)
+
+	for _, area := range areas {
+		src, err := os.ReadFile(area.Path())
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := area.Encode()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != string(src) {
+			t.Errorf("%s: Encode is not lossless:\n%s", area.Path(), got)
+		}
+	}
+
+	areas, err = ParseSupplyAreaDir(t.TempDir())
+	if err != nil || areas != nil {
+		t.Fatalf("expect no supply areas without map/supplyareas, got %v, %v", areas, err)
+	}
+}
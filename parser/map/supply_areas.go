@@ -0,0 +1,79 @@
+package _map
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	stlslices "github.com/kkkunny/stl/container/slices"
+
+	"github.com/kkkunny/TEW-hoi4/parser/pdx"
+)
+
+// SupplyArea map/supplyareas中的补给区域，由若干地区组成
+type SupplyArea struct {
+	ID     int64   `json:"id" pdx:"id"`
+	Name   string  `json:"name,omitempty" pdx:"name,quoted"`
+	Value  int64   `json:"value,omitempty" pdx:"value,omitempty"`
+	States []int64 `json:"states" pdx:"states"`
+	// Rest 其他未建模的语句
+	Rest pdx.Statements `json:"-" pdx:",remain"`
+
+	// file 读取时的语法树
+	file *pdx.File
+}
+
+func ParseSupplyArea(path string) (*SupplyArea, error) {
+	file, err := pdx.ParseFile(path)
+	if err != nil {
+		return nil, err
+	}
+	a := file.Body.Get("supply_area")
+	if a == nil {
+		return nil, errors.New("missing `supply_area`")
+	}
+	var area SupplyArea
+	err = pdx.UnmarshalNode(a, &area)
+	if err != nil {
+		return nil, err
+	}
+	area.file = file
+	return &area, nil
+}
+
+// Path 返回补给区域文件路径，不是由ParseSupplyArea得到时返回空字符串
+func (area *SupplyArea) Path() string {
+	if area.file == nil {
+		return ""
+	}
+	return area.file.Filename
+}
+
+// Encode 编码为补给区域文件，由ParseSupplyArea得到的补给区域只会修改发生变化的语句
+func (area *SupplyArea) Encode() ([]byte, error) {
+	return pdx.EncodeFile(area.file, &struct {
+		Area *SupplyArea `pdx:"supply_area"`
+	}{Area: area})
+}
+
+// ParseSupplyAreaDir 读取mod中的所有补给区域，新版本游戏已移除补给区域，目录不存在时返回nil
+func ParseSupplyAreaDir(modPath string) ([]*SupplyArea, error) {
+	dir := filepath.Join(modPath, "map", "supplyareas")
+	areaInfos, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return stlslices.FlatMapError(areaInfos, func(_ int, e os.DirEntry) ([]*SupplyArea, error) {
+		if e.IsDir() || filepath.Ext(e.Name()) != ".txt" {
+			return nil, nil
+		}
+		area, err := ParseSupplyArea(filepath.Join(dir, e.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("supply area %s parse error: %s", e.Name(), err.Error())
+		}
+		return []*SupplyArea{area}, nil
+	})
+}
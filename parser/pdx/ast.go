@@ -0,0 +1,255 @@
+package pdx
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Node 语法树节点
+type Node interface {
+	Pos() Pos
+	End() Pos
+	// tokens 按源码顺序返回该节点包含的所有词法单元
+	tokens(yield func(tok *Token) bool) bool
+}
+
+// Value 值，可以是标量或块
+type Value interface {
+	Node
+	value()
+}
+
+// Statement 块中的语句，可以是赋值或单独的值（列表元素）
+type Statement interface {
+	Node
+	statement()
+}
+
+// Scalar 标量，包括裸字（标识符、数字、日期等）与带引号的字符串
+type Scalar struct {
+	Tok Token
+}
+
+func (s *Scalar) Pos() Pos { return s.Tok.Pos }
+func (s *Scalar) End() Pos { return s.Tok.End() }
+func (s *Scalar) tokens(yield func(tok *Token) bool) bool {
+	return yield(&s.Tok)
+}
+func (*Scalar) value() {}
+
+// Quoted 是否为带引号的字符串
+func (s *Scalar) Quoted() bool {
+	return s.Tok.Kind == TokenString
+}
+
+// Value 返回去除引号后的文本
+func (s *Scalar) Value() string {
+	if !s.Quoted() {
+		return s.Tok.Text
+	}
+	text := strings.TrimSuffix(strings.TrimPrefix(s.Tok.Text, `"`), `"`)
+	return strings.ReplaceAll(strings.ReplaceAll(text, `\"`, `"`), `\\`, `\`)
+}
+
+func (s *Scalar) Int() (int64, error) {
+	return strconv.ParseInt(s.Value(), 10, 64)
+}
+
+func (s *Scalar) Float() (float64, error) {
+	return strconv.ParseFloat(s.Value(), 64)
+}
+
+// Bool 解析yes/no
+func (s *Scalar) Bool() (bool, error) {
+	switch s.Value() {
+	case "yes":
+		return true, nil
+	case "no":
+		return false, nil
+	default:
+		return false, &strconv.NumError{Func: "Bool", Num: s.Value(), Err: strconv.ErrSyntax}
+	}
+}
+
+// Block 块，如 { a = b }、{ 1 2 3 }，以及带标签的块如 rgb { 1 2 3 }
+type Block struct {
+	Tag    *Token
+	LBrace Token
+	Body   Statements
+	RBrace Token
+}
+
+func (b *Block) Pos() Pos {
+	if b.Tag != nil {
+		return b.Tag.Pos
+	}
+	return b.LBrace.Pos
+}
+func (b *Block) End() Pos { return b.RBrace.End() }
+func (b *Block) tokens(yield func(tok *Token) bool) bool {
+	if b.Tag != nil && !yield(b.Tag) {
+		return false
+	}
+	return yield(&b.LBrace) && b.Body.tokens(yield) && yield(&b.RBrace)
+}
+func (*Block) value() {}
+
+// TagName 返回块标签，如rgb、hsv，无标签时返回空字符串
+func (b *Block) TagName() string {
+	if b.Tag == nil {
+		return ""
+	}
+	return b.Tag.Text
+}
+
+// IsList 块内是否只有单独的值
+func (b *Block) IsList() bool {
+	for _, stmt := range b.Body {
+		if _, ok := stmt.(*Item); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// Assignment 赋值语句，如 key = value、key < value
+type Assignment struct {
+	Key   *Scalar
+	Op    Token
+	Value Value
+}
+
+func (a *Assignment) Pos() Pos { return a.Key.Pos() }
+func (a *Assignment) End() Pos { return a.Value.End() }
+func (a *Assignment) tokens(yield func(tok *Token) bool) bool {
+	return a.Key.tokens(yield) && yield(&a.Op) && a.Value.tokens(yield)
+}
+func (*Assignment) statement() {}
+
+// Name 返回键名
+func (a *Assignment) Name() string {
+	return a.Key.Value()
+}
+
+// Item 单独出现的值，如列表 { 1 2 3 } 中的元素
+type Item struct {
+	Value Value
+}
+
+func (i *Item) Pos() Pos { return i.Value.Pos() }
+func (i *Item) End() Pos { return i.Value.End() }
+func (i *Item) tokens(yield func(tok *Token) bool) bool {
+	return i.Value.tokens(yield)
+}
+func (*Item) statement() {}
+
+// Statements 语句列表
+type Statements []Statement
+
+func (s Statements) tokens(yield func(tok *Token) bool) bool {
+	for _, stmt := range s {
+		if !stmt.tokens(yield) {
+			return false
+		}
+	}
+	return true
+}
+
+// Get 返回第一个键为key的赋值语句
+func (s Statements) Get(key string) *Assignment {
+	for _, stmt := range s {
+		if a, ok := stmt.(*Assignment); ok && a.Name() == key {
+			return a
+		}
+	}
+	return nil
+}
+
+// GetAll 返回所有键为key的赋值语句
+func (s Statements) GetAll(key string) []*Assignment {
+	var res []*Assignment
+	for _, stmt := range s {
+		if a, ok := stmt.(*Assignment); ok && a.Name() == key {
+			res = append(res, a)
+		}
+	}
+	return res
+}
+
+// Assignments 返回所有赋值语句
+func (s Statements) Assignments() []*Assignment {
+	var res []*Assignment
+	for _, stmt := range s {
+		if a, ok := stmt.(*Assignment); ok {
+			res = append(res, a)
+		}
+	}
+	return res
+}
+
+// Items 返回所有单独的值
+func (s Statements) Items() []Value {
+	var res []Value
+	for _, stmt := range s {
+		if i, ok := stmt.(*Item); ok {
+			res = append(res, i.Value)
+		}
+	}
+	return res
+}
+
+// File 一个脚本文件
+type File struct {
+	Filename string
+	Body     Statements
+	// EOF 保存文件末尾的空白与注释
+	EOF Token
+}
+
+func (f *File) Pos() Pos {
+	if len(f.Body) == 0 {
+		return f.EOF.Pos
+	}
+	return f.Body[0].Pos()
+}
+func (f *File) End() Pos { return f.EOF.Pos }
+func (f *File) tokens(yield func(tok *Token) bool) bool {
+	return f.Body.tokens(yield) && yield(&f.EOF)
+}
+
+// Walk 按源码顺序遍历节点下的所有词法单元
+func Walk(node Node, fn func(tok *Token) bool) {
+	node.tokens(fn)
+}
+
+// firstToken 返回节点的第一个词法单元
+func firstToken(node Node) *Token {
+	var first *Token
+	node.tokens(func(tok *Token) bool {
+		first = tok
+		return false
+	})
+	return first
+}
+
+// lastToken 返回节点的最后一个词法单元
+func lastToken(node Node) *Token {
+	var last *Token
+	node.tokens(func(tok *Token) bool {
+		last = tok
+		return true
+	})
+	return last
+}
+
+// Comments 返回语句前的注释与语句所在行末尾的注释
+func Comments(node Node) []string {
+	var comments []string
+	if first := firstToken(node); first != nil {
+		comments = append(comments, triviaComments(first.Leading)...)
+	}
+	if last := lastToken(node); last != nil {
+		comments = append(comments, triviaComments(last.Trailing)...)
+	}
+	return comments
+}
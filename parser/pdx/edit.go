@@ -0,0 +1,306 @@
+package pdx
+
+import (
+	"strconv"
+	"strings"
+)
+
+// NewWord 创建裸字标量
+func NewWord(text string) *Scalar {
+	return &Scalar{Tok: Token{Kind: TokenWord, Text: text}}
+}
+
+// NewString 创建带引号的字符串标量
+func NewString(text string) *Scalar {
+	return &Scalar{Tok: Token{Kind: TokenString, Text: quote(text)}}
+}
+
+// NewScalar 创建标量，文本无法作为裸字时自动加引号
+func NewScalar(text string) *Scalar {
+	if needQuote(text) {
+		return NewString(text)
+	}
+	return NewWord(text)
+}
+
+func NewInt(v int64) *Scalar {
+	return NewWord(strconv.FormatInt(v, 10))
+}
+
+func NewFloat(v float64) *Scalar {
+	return NewWord(strconv.FormatFloat(v, 'f', -1, 64))
+}
+
+// NewBool 创建yes/no标量
+func NewBool(v bool) *Scalar {
+	if v {
+		return NewWord("yes")
+	}
+	return NewWord("no")
+}
+
+// NewAssignment 创建 key = value 赋值语句
+func NewAssignment(key string, value Value) *Assignment {
+	return NewComparison(key, TokenAssign, value)
+}
+
+// NewComparison 创建使用指定运算符的语句，如 key > value
+func NewComparison(key string, op TokenKind, value Value) *Assignment {
+	k := NewScalar(key)
+	k.Tok.Trailing = " "
+	firstToken(value).Leading = ""
+	return &Assignment{
+		Key:   k,
+		Op:    Token{Kind: op, Text: op.String(), Trailing: " "},
+		Value: value,
+	}
+}
+
+func NewItem(value Value) *Item {
+	return &Item{Value: value}
+}
+
+// NewBlock 创建单行块 { ... }，多行格式可使用Reformat
+func NewBlock(stmts ...Statement) *Block {
+	b := &Block{
+		LBrace: Token{Kind: TokenLBrace, Text: "{", Trailing: " "},
+		RBrace: Token{Kind: TokenRBrace, Text: "}"},
+	}
+	for i, stmt := range stmts {
+		firstToken(stmt).Leading = stringsTernary(i == 0, "", " ")
+		lastToken(stmt).Trailing = ""
+	}
+	b.Body = stmts
+	if len(stmts) != 0 {
+		b.RBrace.Leading = " "
+	}
+	return b
+}
+
+// NewTaggedBlock 创建带标签的块，如 rgb { 1 2 3 }
+func NewTaggedBlock(tag string, stmts ...Statement) *Block {
+	b := NewBlock(stmts...)
+	b.Tag = &Token{Kind: TokenWord, Text: tag, Trailing: " "}
+	return b
+}
+
+// NewList 创建列表块 { v1 v2 ... }
+func NewList(values ...Value) *Block {
+	stmts := make([]Statement, len(values))
+	for i, v := range values {
+		stmts[i] = NewItem(v)
+	}
+	return NewBlock(stmts...)
+}
+
+func stringsTernary(cond bool, t, f string) string {
+	if cond {
+		return t
+	}
+	return f
+}
+
+func needQuote(text string) bool {
+	if text == "" {
+		return true
+	}
+	for i := 0; i < len(text); i++ {
+		if !isWordChar(text[i]) || (text[i] == '!' && i+1 < len(text) && text[i+1] == '=') {
+			return true
+		}
+	}
+	return false
+}
+
+func quote(text string) string {
+	return `"` + strings.ReplaceAll(text, `"`, `\"`) + `"`
+}
+
+// SetValue 修改标量文本，保留原有的引号风格与空白注释
+func (s *Scalar) SetValue(text string) {
+	if s.Quoted() || needQuote(text) {
+		s.Tok.Kind, s.Tok.Text = TokenString, quote(text)
+	} else {
+		s.Tok.Kind, s.Tok.Text = TokenWord, text
+	}
+}
+
+// SetValue 替换赋值语句的值，新值继承旧值两侧的空白与注释
+func (a *Assignment) SetValue(value Value) {
+	if a.Value != nil {
+		firstToken(value).Leading = firstToken(a.Value).Leading
+		lastToken(value).Trailing = lastToken(a.Value).Trailing
+	}
+	a.Value = value
+}
+
+// container 包含语句列表的节点，即Block与File
+type container interface {
+	Node
+	body() *Statements
+	opening() *Token
+	closing() *Token
+}
+
+func (b *Block) body() *Statements { return &b.Body }
+func (b *Block) opening() *Token   { return &b.LBrace }
+func (b *Block) closing() *Token   { return &b.RBrace }
+
+func (f *File) body() *Statements { return &f.Body }
+func (f *File) opening() *Token   { return nil }
+func (f *File) closing() *Token   { return &f.EOF }
+
+// separator 根据已有语句前的空白推导出新语句前的空白
+func separator(leading string) string {
+	i := strings.LastIndexByte(leading, '\n')
+	if i < 0 {
+		return stringsTernary(leading == "", "", " ")
+	}
+	indent := leading[i+1:]
+	if i > 0 && leading[i-1] == '\r' {
+		return "\r\n" + indent
+	}
+	return "\n" + indent
+}
+
+func insertStatement(c container, i int, stmt Statement) {
+	body := c.body()
+	first := firstToken(stmt)
+	_, isFile := c.(*File)
+	defaultSep := stringsTernary(isFile, "\n", " ")
+
+	switch {
+	case len(*body) == 0:
+		closing := c.closing()
+		if isFile {
+			first.Leading = ""
+		} else if strings.Contains(closing.Leading, "\n") {
+			first.Leading = separator(closing.Leading) + "\t"
+		} else {
+			first.Leading = stringsTernary(c.opening().Trailing == "", " ", "")
+			if closing.Leading == "" {
+				closing.Leading = " "
+			}
+		}
+	case i == 0:
+		old := firstToken((*body)[0])
+		first.Leading = old.Leading
+		old.Leading = separator(old.Leading)
+		if old.Leading == "" || (isFile && !strings.Contains(old.Leading, "\n")) {
+			old.Leading = defaultSep
+		}
+	default:
+		prev := (*body)[i-1]
+		prevLast := lastToken(prev)
+		first.Leading = separator(firstToken(prev).Leading)
+		if !strings.Contains(first.Leading, "\n") {
+			switch {
+			case isFile:
+				first.Leading = "\n"
+			case prevLast.Trailing != "" && !strings.ContainsRune(prevLast.Trailing, '#'):
+				// 同一行内的语句间以前一个语句的行尾空白分隔
+				first.Leading = ""
+			default:
+				first.Leading = " "
+			}
+		}
+		if i == len(*body) && !strings.ContainsRune(prevLast.Trailing, '#') {
+			lastToken(stmt).Trailing = prevLast.Trailing
+		}
+	}
+
+	*body = append(*body, nil)
+	copy((*body)[i+1:], (*body)[i:])
+	(*body)[i] = stmt
+}
+
+func removeStatement(c container, stmt Statement) bool {
+	body := c.body()
+	for i, s := range *body {
+		if s != stmt {
+			continue
+		}
+		if i == 0 && len(*body) > 1 {
+			next := firstToken((*body)[1])
+			if _, isFile := c.(*File); isFile || !strings.Contains(next.Leading, "\n") {
+				next.Leading = firstToken(s).Leading
+			}
+		}
+		*body = append((*body)[:i], (*body)[i+1:]...)
+		return true
+	}
+	return false
+}
+
+func setStatement(c container, key string, value Value) *Assignment {
+	if a := c.body().Get(key); a != nil {
+		a.SetValue(value)
+		return a
+	}
+	a := NewAssignment(key, value)
+	insertStatement(c, len(*c.body()), a)
+	return a
+}
+
+func removeAllStatements(c container, key string) int {
+	var n int
+	for _, a := range c.body().GetAll(key) {
+		removeStatement(c, a)
+		n++
+	}
+	return n
+}
+
+// Append 在块末尾追加语句，缩进与相邻语句保持一致
+func (b *Block) Append(stmts ...Statement) {
+	for _, stmt := range stmts {
+		insertStatement(b, len(b.Body), stmt)
+	}
+}
+
+// Insert 在块的第i个语句前插入语句
+func (b *Block) Insert(i int, stmt Statement) {
+	insertStatement(b, i, stmt)
+}
+
+// Remove 删除块中的语句
+func (b *Block) Remove(stmt Statement) bool {
+	return removeStatement(b, stmt)
+}
+
+// RemoveAll 删除块中所有键为key的赋值语句，返回删除的数量
+func (b *Block) RemoveAll(key string) int {
+	return removeAllStatements(b, key)
+}
+
+// Set 修改第一个键为key的赋值语句的值，不存在时追加到末尾
+func (b *Block) Set(key string, value Value) *Assignment {
+	return setStatement(b, key, value)
+}
+
+// Append 在文件末尾追加语句
+func (f *File) Append(stmts ...Statement) {
+	for _, stmt := range stmts {
+		insertStatement(f, len(f.Body), stmt)
+	}
+}
+
+// Insert 在文件的第i个语句前插入语句
+func (f *File) Insert(i int, stmt Statement) {
+	insertStatement(f, i, stmt)
+}
+
+// Remove 删除文件中的语句
+func (f *File) Remove(stmt Statement) bool {
+	return removeStatement(f, stmt)
+}
+
+// RemoveAll 删除文件中所有键为key的赋值语句，返回删除的数量
+func (f *File) RemoveAll(key string) int {
+	return removeAllStatements(f, key)
+}
+
+// Set 修改第一个键为key的赋值语句的值，不存在时追加到末尾
+func (f *File) Set(key string, value Value) *Assignment {
+	return setStatement(f, key, value)
+}
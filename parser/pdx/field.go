@@ -0,0 +1,153 @@
+package pdx
+
+import (
+	"fmt"
+	"image/color"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/kkkunny/stl/container/optional"
+)
+
+// 结构体字段标签格式：`pdx:"name,opt1,opt2"`
+// multi：重复出现的键依次存入切片
+// rgb、hsv：颜色值，编码时使用对应的标签块
+// quoted：编码时字符串总是带引号
+// omitempty：编码时忽略零值
+// remain：类型为Statements的字段，存放所有未匹配的语句
+type fieldInfo struct {
+	index     []int
+	name      string
+	multi     bool
+	quoted    bool
+	omitempty bool
+	remain    bool
+	colorMode string
+}
+
+var fieldCache sync.Map
+
+func structFields(t reflect.Type) []*fieldInfo {
+	if cached, ok := fieldCache.Load(t); ok {
+		return cached.([]*fieldInfo)
+	}
+	var fields []*fieldInfo
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+		tag, ok := sf.Tag.Lookup("pdx")
+		if tag == "-" {
+			continue
+		}
+		if !ok && sf.Anonymous && sf.Type.Kind() == reflect.Struct {
+			for _, f := range structFields(sf.Type) {
+				inner := *f
+				inner.index = append([]int{i}, f.index...)
+				fields = append(fields, &inner)
+			}
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		if name == "" {
+			name = sf.Name
+		}
+		info := &fieldInfo{index: []int{i}, name: name}
+		for _, opt := range strings.Split(opts, ",") {
+			switch opt {
+			case "multi":
+				info.multi = true
+			case "quoted":
+				info.quoted = true
+			case "omitempty":
+				info.omitempty = true
+			case "remain":
+				info.remain = true
+			case "rgb", "hsv":
+				info.colorMode = opt
+			}
+		}
+		fields = append(fields, info)
+	}
+	fieldCache.Store(t, fields)
+	return fields
+}
+
+// FieldKeys 返回结构体各字段对应的键名
+func FieldKeys(v any) []string {
+	t := reflect.TypeOf(v)
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	var keys []string
+	for _, f := range structFields(t) {
+		if !f.remain {
+			keys = append(keys, f.name)
+		}
+	}
+	return keys
+}
+
+// optional.Optional[T]只通过导出的方法读取，通过optional.Some构造，不依赖其内部结构
+const optionalPkgPath = "github.com/kkkunny/stl/container/optional"
+
+// optionalConstructors 已登记的Optional类型的构造函数
+var optionalConstructors sync.Map
+
+// RegisterOptional 登记optional.Optional[T]，使其可以解码；基本类型和颜色已预先登记
+func RegisterOptional[T any]() {
+	optionalConstructors.Store(reflect.TypeFor[optional.Optional[T]](), func(elem reflect.Value) reflect.Value {
+		var v T
+		reflect.ValueOf(&v).Elem().Set(elem)
+		return reflect.ValueOf(optional.Some(v))
+	})
+}
+
+func init() {
+	RegisterOptional[string]()
+	RegisterOptional[bool]()
+	RegisterOptional[int]()
+	RegisterOptional[int64]()
+	RegisterOptional[uint8]()
+	RegisterOptional[uint64]()
+	RegisterOptional[float64]()
+	RegisterOptional[[3]uint8]()
+	RegisterOptional[color.Color]()
+}
+
+func isOptional(t reflect.Type) bool {
+	if t.Kind() != reflect.Struct || t.PkgPath() != optionalPkgPath || !strings.HasPrefix(t.Name(), "Optional[") {
+		return false
+	}
+	_, isSome := t.MethodByName("IsSome")
+	_, mustValue := t.MethodByName("MustValue")
+	return isSome && mustValue
+}
+
+func optionalElem(t reflect.Type) reflect.Type {
+	method, _ := t.MethodByName("MustValue")
+	return method.Type.Out(0)
+}
+
+// getOptional 返回Optional中的值，为None时返回false
+func getOptional(v reflect.Value) (reflect.Value, bool) {
+	if !v.MethodByName("IsSome").Call(nil)[0].Bool() {
+		return reflect.Value{}, false
+	}
+	// 复制到可寻址的值中，以便调用指针接收者的方法
+	elem := reflect.New(optionalElem(v.Type())).Elem()
+	elem.Set(v.MethodByName("MustValue").Call(nil)[0])
+	return elem, true
+}
+
+// setOptional 将Optional设置为Some(elem)，类型需要先通过RegisterOptional登记
+func setOptional(v reflect.Value, elem reflect.Value) error {
+	constructor, ok := optionalConstructors.Load(v.Type())
+	if !ok {
+		return fmt.Errorf("pdx: %s is not registered, call pdx.RegisterOptional first", v.Type())
+	}
+	v.Set(constructor.(func(reflect.Value) reflect.Value)(elem))
+	return nil
+}
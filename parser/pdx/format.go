@@ -0,0 +1,120 @@
+package pdx
+
+import (
+	"strings"
+)
+
+// Reformat 按标准格式（制表符缩进、每行一条语句）重排节点内的空白，注释会被保留
+// depth为节点所在语句的缩进层级，对File无效
+func Reformat(node Node, depth int) {
+	switch n := node.(type) {
+	case *File:
+		for i, stmt := range n.Body {
+			reformatStatement(stmt, 0)
+			first := firstToken(stmt)
+			first.Leading = commentLines(first.Leading, "") + "\n"
+			if i == 0 {
+				first.Leading = strings.TrimPrefix(first.Leading, "\n")
+			}
+		}
+		n.EOF.Leading = commentLines(n.EOF.Leading, "") + stringsTernary(len(n.Body) == 0, "", "\n")
+	case Statement:
+		reformatStatement(n, depth)
+	case *Block:
+		reformatBlock(n, depth)
+	case *Scalar:
+		n.Tok.Trailing = trailingComment(n.Tok.Trailing)
+	}
+}
+
+func indent(depth int) string {
+	return strings.Repeat("\t", depth)
+}
+
+// commentLines 保留空白中的注释，每条注释单独一行
+func commentLines(trivia string, indent string) string {
+	var buf strings.Builder
+	for _, comment := range triviaComments(trivia) {
+		buf.WriteString("\n")
+		buf.WriteString(indent)
+		buf.WriteString("#")
+		buf.WriteString(comment)
+	}
+	return buf.String()
+}
+
+func trailingComment(trailing string) string {
+	comments := triviaComments(trailing)
+	if len(comments) == 0 {
+		return ""
+	}
+	return " #" + comments[0]
+}
+
+func reformatStatement(stmt Statement, depth int) {
+	switch s := stmt.(type) {
+	case *Assignment:
+		s.Key.Tok.Leading = commentLines(s.Key.Tok.Leading, indent(depth))
+		s.Key.Tok.Trailing = stringsTernary(strings.ContainsRune(s.Key.Tok.Trailing, '#'), trailingComment(s.Key.Tok.Trailing), " ")
+		s.Op.Leading = ""
+		s.Op.Trailing = stringsTernary(strings.ContainsRune(s.Op.Trailing, '#'), trailingComment(s.Op.Trailing), " ")
+		reformatValue(s.Value, depth)
+		firstToken(s.Value).Leading = ""
+	case *Item:
+		comments := commentLines(firstToken(s.Value).Leading, indent(depth))
+		reformatValue(s.Value, depth)
+		firstToken(s.Value).Leading = comments
+	}
+}
+
+func reformatValue(value Value, depth int) {
+	switch v := value.(type) {
+	case *Scalar:
+		v.Tok.Trailing = trailingComment(v.Tok.Trailing)
+	case *Block:
+		reformatBlock(v, depth)
+	}
+}
+
+// isInline 块是否适合单行输出：不含注释的标量列表，如provinces = { 1 2 }和color = rgb { 1 2 3 }
+func isInline(b *Block) bool {
+	for _, stmt := range b.Body {
+		item, ok := stmt.(*Item)
+		if !ok {
+			return false
+		}
+		s, ok := item.Value.(*Scalar)
+		if !ok || strings.ContainsRune(s.Tok.Leading+s.Tok.Trailing, '#') {
+			return false
+		}
+	}
+	return true
+}
+
+func reformatBlock(b *Block, depth int) {
+	if b.Tag != nil {
+		b.Tag.Leading = ""
+		b.Tag.Trailing = " "
+	}
+	b.LBrace.Leading = ""
+	if isInline(b) {
+		b.LBrace.Trailing = " "
+		for i, stmt := range b.Body {
+			s := stmt.(*Item).Value.(*Scalar)
+			s.Tok.Leading = stringsTernary(i == 0, "", " ")
+			s.Tok.Trailing = ""
+		}
+		b.RBrace.Leading = stringsTernary(len(b.Body) == 0, "", " ")
+		b.RBrace.Trailing = trailingComment(b.RBrace.Trailing)
+		return
+	}
+
+	b.LBrace.Trailing = trailingComment(b.LBrace.Trailing)
+	for _, stmt := range b.Body {
+		reformatStatement(stmt, depth+1)
+		first := firstToken(stmt)
+		first.Leading += "\n" + indent(depth+1)
+	}
+	b.RBrace.Leading = commentLines(b.RBrace.Leading, indent(depth+1)) + "\n" + indent(depth)
+	b.RBrace.Trailing = trailingComment(b.RBrace.Trailing)
+}
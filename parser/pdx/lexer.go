@@ -0,0 +1,168 @@
+package pdx
+
+import (
+	"fmt"
+)
+
+// Error 语法错误
+type Error struct {
+	Filename string
+	Pos      Pos
+	Msg      string
+}
+
+func (e *Error) Error() string {
+	if e.Filename == "" {
+		return fmt.Sprintf("%s: %s", e.Pos, e.Msg)
+	}
+	return fmt.Sprintf("%s:%s: %s", e.Filename, e.Pos, e.Msg)
+}
+
+type lexer struct {
+	filename string
+	src      []byte
+	offset   int
+	line     int
+	column   int
+}
+
+func newLexer(filename string, src []byte) *lexer {
+	return &lexer{
+		filename: filename,
+		src:      src,
+		line:     1,
+		column:   1,
+	}
+}
+
+func (l *lexer) pos() Pos {
+	return Pos{Offset: l.offset, Line: l.line, Column: l.column}
+}
+
+func (l *lexer) errorf(pos Pos, format string, args ...any) error {
+	return &Error{Filename: l.filename, Pos: pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+func (l *lexer) peek(n int) byte {
+	if l.offset+n >= len(l.src) {
+		return 0
+	}
+	return l.src[l.offset+n]
+}
+
+func (l *lexer) advance(n int) {
+	for i := 0; i < n && l.offset < len(l.src); i++ {
+		if l.src[l.offset] == '\n' {
+			l.line++
+			l.column = 1
+		} else {
+			l.column++
+		}
+		l.offset++
+	}
+}
+
+func isSpace(ch byte) bool {
+	return ch == ' ' || ch == '\t' || ch == '\r' || ch == '\n' || ch == '\f' || ch == '\v'
+}
+
+func isWordChar(ch byte) bool {
+	switch ch {
+	case '{', '}', '=', '<', '>', '"', '#':
+		return false
+	default:
+		return !isSpace(ch)
+	}
+}
+
+// skipTrivia 跳过空白与注释，stopAtNewline为真时遇到换行即停止
+func (l *lexer) skipTrivia(stopAtNewline bool) string {
+	begin := l.offset
+	if begin == 0 && len(l.src) >= 3 && l.src[0] == 0xEF && l.src[1] == 0xBB && l.src[2] == 0xBF {
+		l.offset += 3
+	}
+	for l.offset < len(l.src) {
+		ch := l.src[l.offset]
+		switch {
+		case ch == '\n' && stopAtNewline:
+			return string(l.src[begin:l.offset])
+		case isSpace(ch):
+			l.advance(1)
+		case ch == '#':
+			for l.offset < len(l.src) && l.src[l.offset] != '\n' {
+				l.advance(1)
+			}
+		default:
+			return string(l.src[begin:l.offset])
+		}
+	}
+	return string(l.src[begin:l.offset])
+}
+
+func (l *lexer) next() (Token, error) {
+	leading := l.skipTrivia(false)
+	tok := Token{Pos: l.pos(), Leading: leading}
+	if l.offset >= len(l.src) {
+		tok.Kind = TokenEOF
+		return tok, nil
+	}
+
+	begin := l.offset
+	switch ch := l.src[l.offset]; {
+	case ch == '{':
+		tok.Kind = TokenLBrace
+		l.advance(1)
+	case ch == '}':
+		tok.Kind = TokenRBrace
+		l.advance(1)
+	case ch == '=':
+		if l.peek(1) == '=' {
+			tok.Kind = TokenEqual
+			l.advance(2)
+		} else {
+			tok.Kind = TokenAssign
+			l.advance(1)
+		}
+	case ch == '<' || ch == '>':
+		tok.Kind = TokenLess
+		if ch == '>' {
+			tok.Kind = TokenGreater
+		}
+		if l.peek(1) == '=' {
+			tok.Kind++
+			l.advance(1)
+		}
+		l.advance(1)
+	case ch == '!' && l.peek(1) == '=':
+		tok.Kind = TokenNotEqual
+		l.advance(2)
+	case ch == '"':
+		tok.Kind = TokenString
+		l.advance(1)
+		for {
+			if l.offset >= len(l.src) {
+				return tok, l.errorf(tok.Pos, "unterminated string")
+			}
+			c := l.src[l.offset]
+			if c == '\\' && l.offset+1 < len(l.src) {
+				l.advance(2)
+				continue
+			}
+			l.advance(1)
+			if c == '"' {
+				break
+			}
+		}
+	default:
+		tok.Kind = TokenWord
+		for l.offset < len(l.src) && isWordChar(l.src[l.offset]) {
+			if l.src[l.offset] == '!' && l.peek(1) == '=' {
+				break
+			}
+			l.advance(1)
+		}
+	}
+	tok.Text = string(l.src[begin:l.offset])
+	tok.Trailing = l.skipTrivia(true)
+	return tok, nil
+}
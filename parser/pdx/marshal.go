@@ -0,0 +1,242 @@
+package pdx
+
+import (
+	"cmp"
+	"fmt"
+	"image/color"
+	"reflect"
+	"slices"
+	"strconv"
+
+	"github.com/lucasb-eyer/go-colorful"
+
+	"github.com/kkkunny/TEW-hoi4/util"
+)
+
+// Marshaler 自定义编码
+type Marshaler interface {
+	MarshalPDX() (Value, error)
+}
+
+var marshalerType = reflect.TypeFor[Marshaler]()
+
+// Marshal 将结构体或map编码为标准格式的脚本
+func Marshal(v any) ([]byte, error) {
+	file, err := MarshalFile(v)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(file.Encode()), nil
+}
+
+// MarshalFile 将结构体或map编码为语法树
+func MarshalFile(v any) (*File, error) {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer || rv.Kind() == reflect.Interface {
+		rv = rv.Elem()
+	}
+	stmts, err := encodeStatements(rv)
+	if err != nil {
+		return nil, err
+	}
+	file := &File{Body: stmts, EOF: Token{Kind: TokenEOF}}
+	Reformat(file, 0)
+	return file, nil
+}
+
+// EncodeFile 将结构体或map编码为脚本，file不为空时写回到file中，只修改发生变化的语句
+// 写回失败时返回错误，此时file可能已被部分修改，不应再使用
+func EncodeFile(file *File, v any) ([]byte, error) {
+	if file == nil {
+		return Marshal(v)
+	}
+	err := Patch(file, v)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(file.Encode()), nil
+}
+
+// MarshalValue 将任意值编码为语法树中的值
+func MarshalValue(v any) (Value, error) {
+	value, err := encodeValue(reflect.ValueOf(v), nil)
+	if err != nil {
+		return nil, err
+	}
+	if value == nil {
+		return nil, fmt.Errorf("pdx: cannot marshal empty value")
+	}
+	return value, nil
+}
+
+func encodeStatements(v reflect.Value) (Statements, error) {
+	switch v.Kind() {
+	case reflect.Struct:
+		return encodeStruct(v)
+	case reflect.Map:
+		return encodeMap(v)
+	default:
+		if v.Type() == statementsType {
+			return v.Interface().(Statements), nil
+		}
+		return nil, fmt.Errorf("pdx: cannot marshal %s as block", v.Type())
+	}
+}
+
+func encodeStruct(v reflect.Value) (Statements, error) {
+	var stmts Statements
+	for _, f := range structFields(v.Type()) {
+		field := v.FieldByIndex(f.index)
+		switch {
+		case f.remain:
+			if field.Type() != statementsType {
+				return nil, fmt.Errorf("pdx: field with `remain` option must be pdx.Statements, but got %s", field.Type())
+			}
+			stmts = append(stmts, field.Interface().(Statements)...)
+		case f.omitempty && field.IsZero():
+		case f.multi:
+			if field.Kind() != reflect.Slice {
+				return nil, fmt.Errorf("pdx: field with `multi` option must be a slice, but got %s", field.Type())
+			}
+			for i := 0; i < field.Len(); i++ {
+				value, err := encodeValue(field.Index(i), f)
+				if err != nil {
+					return nil, err
+				}
+				if value != nil {
+					stmts = append(stmts, NewAssignment(f.name, value))
+				}
+			}
+		default:
+			value, err := encodeValue(field, f)
+			if err != nil {
+				return nil, err
+			}
+			if value != nil {
+				stmts = append(stmts, NewAssignment(f.name, value))
+			}
+		}
+	}
+	return stmts, nil
+}
+
+func encodeMap(v reflect.Value) (Statements, error) {
+	keys := v.MapKeys()
+	slices.SortFunc(keys, func(l, r reflect.Value) int {
+		switch l.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			return cmp.Compare(l.Int(), r.Int())
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			return cmp.Compare(l.Uint(), r.Uint())
+		default:
+			return cmp.Compare(fmt.Sprint(l.Interface()), fmt.Sprint(r.Interface()))
+		}
+	})
+	stmts := make(Statements, 0, len(keys))
+	for _, key := range keys {
+		value, err := encodeValue(v.MapIndex(key), nil)
+		if err != nil {
+			return nil, err
+		}
+		if value != nil {
+			stmts = append(stmts, NewAssignment(fmt.Sprint(key.Interface()), value))
+		}
+	}
+	return stmts, nil
+}
+
+// encodeValue 编码单个值，返回nil表示该值应被忽略（如None与nil指针）
+func encodeValue(v reflect.Value, info *fieldInfo) (Value, error) {
+	if !v.IsValid() {
+		return nil, nil
+	}
+	if v.Type().Implements(marshalerType) {
+		if v.Kind() == reflect.Pointer && v.IsNil() {
+			return nil, nil
+		}
+		return v.Interface().(Marshaler).MarshalPDX()
+	}
+	if v.CanAddr() && v.Addr().Type().Implements(marshalerType) {
+		return v.Addr().Interface().(Marshaler).MarshalPDX()
+	}
+	if v.Type() != colorType && v.Type().Implements(reflect.TypeFor[Value]()) {
+		if v.IsNil() {
+			return nil, nil
+		}
+		return v.Interface().(Value), nil
+	}
+	if isOptional(v.Type()) {
+		elem, ok := getOptional(v)
+		if !ok {
+			return nil, nil
+		}
+		return encodeValue(elem, info)
+	}
+	if v.Type() == colorType || (info != nil && info.colorMode != "") {
+		return encodeColor(v, info)
+	}
+
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			return nil, nil
+		}
+		return encodeValue(v.Elem(), info)
+	case reflect.String:
+		if info != nil && info.quoted {
+			return NewString(v.String()), nil
+		}
+		return NewScalar(v.String()), nil
+	case reflect.Bool:
+		return NewBool(v.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return NewInt(v.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return NewWord(strconv.FormatUint(v.Uint(), 10)), nil
+	case reflect.Float32, reflect.Float64:
+		return NewWord(strconv.FormatFloat(v.Float(), 'f', -1, v.Type().Bits())), nil
+	case reflect.Slice, reflect.Array:
+		values := make([]Value, 0, v.Len())
+		for i := 0; i < v.Len(); i++ {
+			value, err := encodeValue(v.Index(i), nil)
+			if err != nil {
+				return nil, err
+			}
+			if value != nil {
+				values = append(values, value)
+			}
+		}
+		return NewList(values...), nil
+	case reflect.Struct, reflect.Map:
+		stmts, err := encodeStatements(v)
+		if err != nil {
+			return nil, err
+		}
+		return NewBlock(stmts...), nil
+	default:
+		return nil, fmt.Errorf("pdx: cannot marshal %s", v.Type())
+	}
+}
+
+func encodeColor(v reflect.Value, info *fieldInfo) (Value, error) {
+	var clr color.Color
+	switch {
+	case v.Kind() == reflect.Interface && v.IsNil():
+		return nil, nil
+	case v.Type().Implements(colorType):
+		clr = v.Interface().(color.Color)
+	case v.Type() == reflect.TypeFor[[3]uint8]():
+		rgb := v.Interface().([3]uint8)
+		clr = util.NewRGB(rgb[0], rgb[1], rgb[2])
+	default:
+		return nil, fmt.Errorf("pdx: cannot marshal %s as color", v.Type())
+	}
+
+	if info != nil && info.colorMode == "hsv" {
+		c, _ := colorful.MakeColor(clr)
+		hue, sat, value := c.Hsv()
+		return NewTaggedBlock("hsv", NewItem(NewFloat(hue/360)), NewItem(NewFloat(sat)), NewItem(NewFloat(value))), nil
+	}
+	r, g, b := util.GetRGB(clr)
+	return NewTaggedBlock("rgb", NewItem(NewInt(int64(r))), NewItem(NewInt(int64(g))), NewItem(NewInt(int64(b)))), nil
+}
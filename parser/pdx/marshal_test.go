@@ -0,0 +1,158 @@
+package pdx
+
+import (
+	"image/color"
+	"reflect"
+	"testing"
+
+	"github.com/kkkunny/stl/container/optional"
+
+	"github.com/kkkunny/TEW-hoi4/util"
+)
+
+type testState struct {
+	ID         int64                       `pdx:"id"`
+	Name       string                      `pdx:"name,quoted"`
+	Impassable bool                        `pdx:"impassable,omitempty"`
+	Manpower   optional.Optional[int64]    `pdx:"manpower"`
+	Category   optional.Optional[string]   `pdx:"state_category"`
+	Resources  map[string]float64          `pdx:"resources,omitempty"`
+	Provinces  []int64                     `pdx:"provinces"`
+	Color      color.Color                 `pdx:"color,rgb"`
+	MapColor   optional.Optional[[3]uint8] `pdx:"map_color,rgb"`
+	History    struct {
+		Owner     string                     `pdx:"owner"`
+		Cores     []string                   `pdx:"add_core_of,multi"`
+		Buildings map[int64]map[string]int64 `pdx:"buildings"`
+		Rest      Statements                 `pdx:",remain"`
+	} `pdx:"history"`
+}
+
+const testStateSource = `id = 1
+name = "STATE_1"
+manpower = 1000
+resources = {
+	oil = 12.5
+	steel = 3
+}
+provinces = { 3 1 2 }
+color = rgb { 255 0 12 }
+map_color = rgb { 1 2 3 }
+history = {
+	owner = FRA
+	add_core_of = FRA
+	add_core_of = GER
+	buildings = {
+		12 = {
+			naval_base = 1
+		}
+	}
+	1939.1.1 = {
+		owner = GER
+	}
+}
+`
+
+func TestUnmarshal(t *testing.T) {
+	var state testState
+	if err := Unmarshal([]byte(testStateSource), &state); err != nil {
+		t.Fatal(err)
+	}
+	if state.ID != 1 || state.Name != "STATE_1" || state.Manpower.ValueWith(0) != 1000 || state.Category.IsSome() {
+		t.Fatalf("unexpected state %+v", state)
+	}
+	if !reflect.DeepEqual(state.Resources, map[string]float64{"oil": 12.5, "steel": 3}) {
+		t.Fatalf("unexpected resources %v", state.Resources)
+	}
+	if !reflect.DeepEqual(state.Provinces, []int64{3, 1, 2}) {
+		t.Fatalf("unexpected provinces %v", state.Provinces)
+	}
+	if r, g, b, _ := state.Color.RGBA(); r>>8 != 255 || g>>8 != 0 || b>>8 != 12 {
+		t.Fatalf("unexpected color %v", state.Color)
+	}
+	if state.MapColor.ValueWith() != [3]uint8{1, 2, 3} {
+		t.Fatalf("unexpected map color %v", state.MapColor)
+	}
+	if state.History.Owner != "FRA" || !reflect.DeepEqual(state.History.Cores, []string{"FRA", "GER"}) {
+		t.Fatalf("unexpected history %+v", state.History)
+	}
+	if state.History.Buildings[12]["naval_base"] != 1 {
+		t.Fatalf("unexpected buildings %v", state.History.Buildings)
+	}
+	if len(state.History.Rest) != 1 || state.History.Rest[0].(*Assignment).Name() != "1939.1.1" {
+		t.Fatalf("unexpected rest statements %v", state.History.Rest)
+	}
+
+	data, err := Marshal(&state)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != testStateSource {
+		t.Fatalf("marshal failed:\nexpect: %q\nactual: %q", testStateSource, data)
+	}
+}
+
+func TestUnmarshalError(t *testing.T) {
+	var state testState
+	err := Unmarshal([]byte("id = 1\nmanpower = abc"), &state)
+	if pdxErr, ok := err.(*Error); !ok || pdxErr.Pos.Line != 2 {
+		t.Fatalf("expect error at line 2, but got %v", err)
+	}
+}
+
+func TestRegisterOptional(t *testing.T) {
+	type value struct {
+		Level optional.Optional[int32] `pdx:"level"`
+	}
+	var v value
+	if err := Unmarshal([]byte("level = 3"), &v); err == nil {
+		t.Fatal("expect error for unregistered optional type")
+	}
+	RegisterOptional[int32]()
+	if err := Unmarshal([]byte("level = 3"), &v); err != nil {
+		t.Fatal(err)
+	}
+	if got, ok := v.Level.Value(); !ok || got != 3 {
+		t.Fatalf("expect Some(3), got %v", v.Level)
+	}
+	data, err := Marshal(&v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "level = 3\n" {
+		t.Fatalf("unexpected marshal result %q", data)
+	}
+}
+
+func TestHSVColor(t *testing.T) {
+	var v struct {
+		Color color.Color `pdx:"color,hsv"`
+	}
+	// hoi4中色相也在0~1之间，0.5为青色
+	if err := Unmarshal([]byte("color = hsv { 0.5 1 1 }\n"), &v); err != nil {
+		t.Fatal(err)
+	}
+	if r, g, b := util.GetRGB(v.Color); r != 0 || g != 255 || b != 255 {
+		t.Fatalf("unexpected color %d %d %d", r, g, b)
+	}
+	data, err := Marshal(&v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "color = hsv { 0.5 1 1 }\n" {
+		t.Fatalf("unexpected marshal result:\n%s", data)
+	}
+}
+
+func TestMarshalRGB64(t *testing.T) {
+	v := struct {
+		Color color.Color `pdx:"color,rgb"`
+	}{Color: color.RGBA64{R: 0xFFFF, G: 0x8000, B: 0x0100, A: 0xFFFF}}
+	data, err := Marshal(&v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "color = rgb { 255 128 1 }\n" {
+		t.Fatalf("unexpected marshal result:\n%s", data)
+	}
+}
@@ -0,0 +1,117 @@
+package pdx
+
+import (
+	"os"
+)
+
+type parser struct {
+	lexer *lexer
+	tok   Token
+}
+
+// Parse 解析脚本源码
+func Parse(filename string, src []byte) (*File, error) {
+	p := &parser{lexer: newLexer(filename, src)}
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+	body, err := p.parseStatements(TokenEOF)
+	if err != nil {
+		return nil, err
+	}
+	return &File{Filename: filename, Body: body, EOF: p.tok}, nil
+}
+
+// ParseFile 解析脚本文件
+func ParseFile(path string) (*File, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(path, data)
+}
+
+func (p *parser) next() (err error) {
+	p.tok, err = p.lexer.next()
+	return err
+}
+
+func (p *parser) expect(kind TokenKind) (Token, error) {
+	tok := p.tok
+	if tok.Kind != kind {
+		return tok, p.lexer.errorf(tok.Pos, "expect `%s`, but got `%s`", kind, tok.Kind)
+	}
+	return tok, p.next()
+}
+
+func (p *parser) parseStatements(end TokenKind) (Statements, error) {
+	var stmts Statements
+	for p.tok.Kind != end {
+		if p.tok.Kind == TokenEOF {
+			return nil, p.lexer.errorf(p.tok.Pos, "unexpected EOF, missing `}`")
+		}
+		stmt, err := p.parseStatement()
+		if err != nil {
+			return nil, err
+		}
+		stmts = append(stmts, stmt)
+	}
+	return stmts, nil
+}
+
+func (p *parser) parseStatement() (Statement, error) {
+	value, err := p.parseValue()
+	if err != nil {
+		return nil, err
+	}
+	if !p.tok.Kind.IsOperator() {
+		return &Item{Value: value}, nil
+	}
+	key, ok := value.(*Scalar)
+	if !ok {
+		return nil, p.lexer.errorf(p.tok.Pos, "unexpected `%s` after block", p.tok.Kind)
+	}
+	op := p.tok
+	if err = p.next(); err != nil {
+		return nil, err
+	}
+	value, err = p.parseValue()
+	if err != nil {
+		return nil, err
+	}
+	return &Assignment{Key: key, Op: op, Value: value}, nil
+}
+
+func (p *parser) parseValue() (Value, error) {
+	switch p.tok.Kind {
+	case TokenWord, TokenString:
+		tok := p.tok
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		if tok.Kind == TokenWord && p.tok.Kind == TokenLBrace {
+			return p.parseBlock(&tok)
+		}
+		return &Scalar{Tok: tok}, nil
+	case TokenLBrace:
+		return p.parseBlock(nil)
+	default:
+		return nil, p.lexer.errorf(p.tok.Pos, "unexpected `%s`", p.tok.Kind)
+	}
+}
+
+func (p *parser) parseBlock(tag *Token) (*Block, error) {
+	lbrace, err := p.expect(TokenLBrace)
+	if err != nil {
+		return nil, err
+	}
+	body, err := p.parseStatements(TokenRBrace)
+	if err != nil {
+		return nil, err
+	}
+	rbrace, err := p.expect(TokenRBrace)
+	if err != nil {
+		return nil, err
+	}
+	return &Block{Tag: tag, LBrace: lbrace, Body: body, RBrace: rbrace}, nil
+}
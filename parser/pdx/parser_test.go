@@ -0,0 +1,76 @@
+package pdx
+
+import (
+	"errors"
+	"testing"
+)
+
+const testSource = "\xEF\xBB\xBF# state file\nstate = {\n\tid = 1\n\tname = \"STATE 1\" # quoted\n\tprovinces = {\n\t\t3838 9851\n\t}\n\tcolor = rgb { 255 0 12 }\n\thistory = {\n\t\towner = FRA\n\t\t1939.1.1 = {\n\t\t\tadd_core_of = GER\n\t\t}\n\t}\n\tlimit = { num_of_factories > 10 has_war != yes }\n}\n"
+
+func TestParse(t *testing.T) {
+	file, err := Parse("test.txt", []byte(testSource))
+	if err != nil {
+		t.Fatal(err)
+	}
+	state := file.Body.Get("state")
+	if state == nil {
+		t.Fatal("missing `state`")
+	}
+	if comments := Comments(state); len(comments) != 1 || comments[0] != " state file" {
+		t.Fatalf("unexpected comments %q", comments)
+	}
+	block := state.Value.(*Block)
+	if pos := block.Body.Get("id").Pos(); pos.Line != 3 || pos.Column != 2 {
+		t.Fatalf("unexpected position %s", pos)
+	}
+
+	name := block.Body.Get("name")
+	if v := name.Value.(*Scalar); !v.Quoted() || v.Value() != "STATE 1" {
+		t.Fatalf("unexpected name %q", v.Tok.Text)
+	}
+	if comments := Comments(name); len(comments) != 1 || comments[0] != " quoted" {
+		t.Fatalf("unexpected comments %q", comments)
+	}
+
+	provinces := block.Body.Get("provinces").Value.(*Block)
+	if !provinces.IsList() || len(provinces.Body.Items()) != 2 {
+		t.Fatal("`provinces` is not a list of two items")
+	}
+
+	clr := block.Body.Get("color").Value.(*Block)
+	if clr.TagName() != "rgb" || len(clr.Body.Items()) != 3 {
+		t.Fatalf("unexpected color %s", clr.TagName())
+	}
+
+	history := block.Body.Get("history").Value.(*Block)
+	dated := history.Body.Get("1939.1.1").Value.(*Block)
+	if core := dated.Body.Get("add_core_of").Value.(*Scalar).Value(); core != "GER" {
+		t.Fatalf("unexpected core %s", core)
+	}
+
+	limit := block.Body.Get("limit").Value.(*Block)
+	if op := limit.Body.Get("num_of_factories").Op.Kind; op != TokenGreater {
+		t.Fatalf("unexpected operator %s", op)
+	}
+	if op := limit.Body.Get("has_war").Op.Kind; op != TokenNotEqual {
+		t.Fatalf("unexpected operator %s", op)
+	}
+}
+
+func TestParseError(t *testing.T) {
+	for src, pos := range map[string]Pos{
+		"a = {\n\tb = c\n":  {Offset: 13, Line: 3, Column: 1},
+		"a = }":             {Offset: 4, Line: 1, Column: 5},
+		"a = \"b\nc = d":    {Offset: 4, Line: 1, Column: 5},
+		"a = { b = c } = d": {Offset: 14, Line: 1, Column: 15},
+	} {
+		_, err := Parse("test.txt", []byte(src))
+		var pdxErr *Error
+		if !errors.As(err, &pdxErr) {
+			t.Fatalf("%q: expect syntax error, but got %v", src, err)
+		}
+		if pdxErr.Pos != pos {
+			t.Fatalf("%q: expect error at %s, but got %s", src, pos, pdxErr.Pos)
+		}
+	}
+}
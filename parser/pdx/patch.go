@@ -0,0 +1,385 @@
+package pdx
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Patcher 自定义写回已有的块
+type Patcher interface {
+	PatchPDX(block *Block) error
+}
+
+var patcherType = reflect.TypeFor[Patcher]()
+
+// Patch 将v写回到已有的语法树节点（File或Block）中
+// 只修改值发生变化的语句，未变化的语句、注释与空白保持原样，新增的语句按相邻语句的缩进插入
+// 没有对应字段的语句不会被修改，除非结构体中有remain字段
+func Patch(node Node, v any) error {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer || rv.Kind() == reflect.Interface {
+		rv = rv.Elem()
+	}
+	switch n := node.(type) {
+	case *File:
+		return patchContainer(n, rv, 0)
+	case *Block:
+		return patchContainer(n, rv, innerDepth(n))
+	case *Assignment:
+		block, ok := n.Value.(*Block)
+		if !ok {
+			return fmt.Errorf("pdx: cannot patch scalar `%s`", n.Name())
+		}
+		return patchContainer(block, rv, innerDepth(block))
+	default:
+		return fmt.Errorf("pdx: cannot patch %T", node)
+	}
+}
+
+// Depth 推导块内语句的缩进层级
+func (b *Block) Depth() int {
+	return innerDepth(b)
+}
+
+func innerDepth(b *Block) int {
+	if i := strings.LastIndexByte(b.RBrace.Leading, '\n'); i >= 0 {
+		return strings.Count(b.RBrace.Leading[i+1:], "\t") + 1
+	}
+	if len(b.Body) != 0 {
+		leading := firstToken(b.Body[0]).Leading
+		if i := strings.LastIndexByte(leading, '\n'); i >= 0 {
+			return strings.Count(leading[i+1:], "\t")
+		}
+	}
+	return 1
+}
+
+func patchContainer(c container, v reflect.Value, depth int) error {
+	switch v.Kind() {
+	case reflect.Struct:
+		return patchStruct(c, v, depth)
+	case reflect.Map:
+		return patchMap(c, v, depth)
+	default:
+		return fmt.Errorf("pdx: cannot patch block with %s", v.Type())
+	}
+}
+
+func indexOf(stmts Statements, stmt Statement) int {
+	for i, s := range stmts {
+		if s == stmt {
+			return i
+		}
+	}
+	return -1
+}
+
+func lastIndexOfKey(stmts Statements, key string) int {
+	for i := len(stmts) - 1; i >= 0; i-- {
+		if a, ok := stmts[i].(*Assignment); ok && a.Name() == key {
+			return i
+		}
+	}
+	return -1
+}
+
+func patchStruct(c container, v reflect.Value, depth int) error {
+	var anchor int
+	var remain *fieldInfo
+	names := make(map[string]bool)
+	for _, f := range structFields(v.Type()) {
+		if f.remain {
+			remain = f
+			continue
+		}
+		names[f.name] = true
+
+		field := v.FieldByIndex(f.index)
+		var err error
+		if f.multi {
+			err = patchMulti(c, f, field, anchor, depth)
+		} else {
+			err = patchSingle(c, f, field, anchor, depth)
+		}
+		if err != nil {
+			return err
+		}
+		if i := lastIndexOfKey(*c.body(), f.name); i >= 0 {
+			anchor = i + 1
+		}
+	}
+	if remain == nil {
+		return nil
+	}
+
+	field := v.FieldByIndex(remain.index)
+	if field.Type() != statementsType {
+		return fmt.Errorf("pdx: field with `remain` option must be pdx.Statements, but got %s", field.Type())
+	}
+	rest := field.Interface().(Statements)
+	for _, stmt := range append(Statements(nil), *c.body()...) {
+		if a, ok := stmt.(*Assignment); ok && names[a.Name()] {
+			continue
+		}
+		if indexOf(rest, stmt) < 0 {
+			removeStatement(c, stmt)
+		}
+	}
+	for _, stmt := range rest {
+		if indexOf(*c.body(), stmt) < 0 {
+			Reformat(stmt, depth)
+			insertStatement(c, len(*c.body()), stmt)
+		}
+	}
+	return nil
+}
+
+// unwrap 展开Optional与指针，返回false表示值不存在
+func unwrap(v reflect.Value) (reflect.Value, bool) {
+	for {
+		switch {
+		case isOptional(v.Type()):
+			elem, ok := getOptional(v)
+			if !ok {
+				return v, false
+			}
+			v = elem
+		case v.Kind() == reflect.Pointer && !v.Type().Implements(marshalerType) && !v.Type().Implements(reflect.TypeFor[Value]()):
+			if v.IsNil() {
+				return v, false
+			}
+			v = v.Elem()
+		default:
+			return v, true
+		}
+	}
+}
+
+// equalValue 判断语法树中的值解码后是否与v相等
+func equalValue(value Value, v reflect.Value, info *fieldInfo) bool {
+	tmp := reflect.New(v.Type()).Elem()
+	if err := (&decoder{}).decodeValue(value, tmp, info); err != nil {
+		return false
+	}
+	return reflect.DeepEqual(tmp.Interface(), v.Interface())
+}
+
+// isBlockPatchable 值是否可以递归地写入已有的块
+func isBlockPatchable(v reflect.Value, info *fieldInfo) bool {
+	if v.Type().Implements(marshalerType) || reflect.PointerTo(v.Type()).Implements(marshalerType) || reflect.PointerTo(v.Type()).Implements(unmarshalerType) {
+		return false
+	}
+	if v.Type() == colorType || (info != nil && info.colorMode != "") {
+		return false
+	}
+	return v.Kind() == reflect.Struct || v.Kind() == reflect.Map
+}
+
+func isScalarList(v reflect.Value) bool {
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return false
+	}
+	switch v.Type().Elem().Kind() {
+	case reflect.String, reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	default:
+		return false
+	}
+}
+
+func patchSingle(c container, f *fieldInfo, field reflect.Value, anchor int, depth int) error {
+	existing := c.body().GetAll(f.name)
+	if len(existing) != 0 {
+		target := existing[len(existing)-1]
+		if equalValue(target.Value, field, f) {
+			return nil
+		}
+	}
+
+	inner, ok := unwrap(field)
+	if !ok || (f.omitempty && field.IsZero()) {
+		for _, a := range existing {
+			removeStatement(c, a)
+		}
+		return nil
+	}
+	if len(existing) != 0 {
+		target := existing[len(existing)-1]
+		if block, ok := target.Value.(*Block); ok && block.Tag == nil {
+			switch {
+			case inner.CanAddr() && inner.Addr().Type().Implements(patcherType):
+				return inner.Addr().Interface().(Patcher).PatchPDX(block)
+			case isBlockPatchable(inner, f):
+				return patchContainer(block, inner, depth+1)
+			case isScalarList(inner):
+				return patchList(block, inner)
+			}
+		}
+	}
+
+	value, err := encodeValue(inner, f)
+	if err != nil {
+		return err
+	}
+	if value == nil {
+		return nil
+	}
+	Reformat(value, depth)
+	if len(existing) != 0 {
+		existing[len(existing)-1].SetValue(value)
+		return nil
+	}
+	a := NewAssignment(f.name, value)
+	Reformat(a, depth)
+	insertStatement(c, anchor, a)
+	return nil
+}
+
+func patchMulti(c container, f *fieldInfo, field reflect.Value, anchor int, depth int) error {
+	if field.Kind() != reflect.Slice {
+		return fmt.Errorf("pdx: field with `multi` option must be a slice, but got %s", field.Type())
+	}
+	used := make([]bool, field.Len())
+	for _, a := range c.body().GetAll(f.name) {
+		found := false
+		for i := range used {
+			if !used[i] && equalValue(a.Value, field.Index(i), f) {
+				used[i], found = true, true
+				break
+			}
+		}
+		if !found {
+			removeStatement(c, a)
+		}
+	}
+
+	pos := anchor
+	if i := lastIndexOfKey(*c.body(), f.name); i >= 0 {
+		pos = i + 1
+	}
+	for i, u := range used {
+		if u {
+			continue
+		}
+		value, err := encodeValue(field.Index(i), f)
+		if err != nil {
+			return err
+		}
+		if value == nil {
+			continue
+		}
+		a := NewAssignment(f.name, value)
+		Reformat(a, depth)
+		insertStatement(c, pos, a)
+		pos++
+	}
+	return nil
+}
+
+func patchMap(c container, v reflect.Value, depth int) error {
+	seen := make(map[any]bool, v.Len())
+	for _, a := range c.body().Assignments() {
+		key := reflect.New(v.Type().Key()).Elem()
+		if err := (&decoder{}).decodeValue(a.Key, key, nil); err != nil {
+			continue
+		}
+		elem := v.MapIndex(key)
+		if !elem.IsValid() || seen[key.Interface()] {
+			removeStatement(c, a)
+			continue
+		}
+		seen[key.Interface()] = true
+		if equalValue(a.Value, elem, nil) {
+			continue
+		}
+		inner, ok := unwrap(elem)
+		if !ok {
+			removeStatement(c, a)
+			continue
+		}
+		if block, ok := a.Value.(*Block); ok && block.Tag == nil && inner.CanAddr() && inner.Addr().Type().Implements(patcherType) {
+			if err := inner.Addr().Interface().(Patcher).PatchPDX(block); err != nil {
+				return err
+			}
+			continue
+		} else if ok && block.Tag == nil && isBlockPatchable(inner, nil) {
+			if err := patchContainer(block, inner, depth+1); err != nil {
+				return err
+			}
+			continue
+		}
+		value, err := encodeValue(inner, nil)
+		if err != nil {
+			return err
+		}
+		Reformat(value, depth)
+		a.SetValue(value)
+	}
+
+	missing := reflect.MakeMap(v.Type())
+	for iter := v.MapRange(); iter.Next(); {
+		if !seen[iter.Key().Interface()] {
+			missing.SetMapIndex(iter.Key(), iter.Value())
+		}
+	}
+	stmts, err := encodeMap(missing)
+	if err != nil {
+		return err
+	}
+	for _, stmt := range stmts {
+		Reformat(stmt, depth)
+		insertStatement(c, len(*c.body()), stmt)
+	}
+	return nil
+}
+
+func patchList(b *Block, v reflect.Value) error {
+	if !b.IsList() {
+		value, err := encodeValue(v, nil)
+		if err != nil {
+			return err
+		}
+		b.Body = nil
+		b.Append(value.(*Block).Body...)
+		return nil
+	}
+
+	desired := make([]string, v.Len())
+	remaining := make(map[string]int, v.Len())
+	for i := range desired {
+		value, err := encodeValue(v.Index(i), nil)
+		if err != nil {
+			return err
+		}
+		desired[i] = value.(*Scalar).Value()
+		remaining[desired[i]]++
+	}
+	for _, stmt := range append(Statements(nil), b.Body...) {
+		s, ok := stmt.(*Item).Value.(*Scalar)
+		if ok && remaining[s.Value()] > 0 {
+			remaining[s.Value()]--
+			continue
+		}
+		b.Remove(stmt)
+	}
+	for _, text := range desired {
+		if remaining[text] > 0 {
+			remaining[text]--
+			b.Append(NewItem(NewScalar(text)))
+		}
+	}
+	if equalValue(b, v, nil) {
+		return nil
+	}
+
+	// 顺序不一致时按期望的顺序重建列表，保留原有的括号布局
+	b.Body = nil
+	for _, text := range desired {
+		b.Append(NewItem(NewScalar(text)))
+	}
+	return nil
+}
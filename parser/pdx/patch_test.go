@@ -0,0 +1,76 @@
+package pdx
+
+import (
+	"testing"
+
+	"github.com/kkkunny/stl/container/optional"
+)
+
+func TestPatch(t *testing.T) {
+	src := "# header\nid = 1\nname = \"STATE_1\"\nresources = {\n\toil = 12.5 # keep\n}\nprovinces = {\n\t3 1 2 \n}\ncolor = rgb { 255 0 12 }\nhistory = {\n\towner = FRA\n\tadd_core_of = FRA\n\tadd_core_of = GER\n\tbuildings = {\n\t\t12 = {\n\t\t\tnaval_base = 1\n\t\t}\n\t}\n\t1939.1.1 = {\n\t\towner = GER\n\t}\n}\n"
+	file, err := Parse("test.txt", []byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var state testState
+	if err = UnmarshalNode(file, &state); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = Patch(file, &state); err != nil {
+		t.Fatal(err)
+	}
+	if res := file.Encode(); res != src {
+		t.Fatalf("unchanged patch modified file:\nexpect: %q\nactual: %q", src, res)
+	}
+
+	state.Manpower = optional.Some[int64](500)
+	state.Resources["steel"] = 3
+	state.Provinces = []int64{3, 2, 4}
+	state.History.Owner = "ITA"
+	state.History.Cores = []string{"GER", "ITA"}
+	state.History.Buildings[12]["bunker"] = 2
+	state.History.Buildings[13] = map[string]int64{"naval_base": 3}
+	expect := "# header\nid = 1\nname = \"STATE_1\"\nmanpower = 500\nresources = {\n\toil = 12.5 # keep\n\tsteel = 3\n}\nprovinces = {\n\t3 2 4 \n}\ncolor = rgb { 255 0 12 }\nhistory = {\n\towner = ITA\n\tadd_core_of = GER\n\tadd_core_of = ITA\n\tbuildings = {\n\t\t12 = {\n\t\t\tnaval_base = 1\n\t\t\tbunker = 2\n\t\t}\n\t\t13 = {\n\t\t\tnaval_base = 3\n\t\t}\n\t}\n\t1939.1.1 = {\n\t\towner = GER\n\t}\n}\n"
+	if err = Patch(file, &state); err != nil {
+		t.Fatal(err)
+	}
+	if res := file.Encode(); res != expect {
+		t.Fatalf("patch failed:\nexpect: %q\nactual: %q", expect, res)
+	}
+}
+
+func TestEncodeFile(t *testing.T) {
+	type value struct {
+		ID    int64  `pdx:"id"`
+		Owner string `pdx:"owner"`
+	}
+	file, err := Parse("test.txt", []byte("id = 1 # keep\nowner = FRA\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := EncodeFile(file, &value{ID: 1, Owner: "GER"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res := string(data); res != "id = 1 # keep\nowner = GER\n" {
+		t.Fatalf("unexpected encode result %q", res)
+	}
+	data, err = EncodeFile(nil, &value{ID: 2, Owner: "ITA"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res := string(data); res != "id = 2\nowner = ITA\n" {
+		t.Fatalf("unexpected encode result %q", res)
+	}
+
+	type invalid struct {
+		Cores string `pdx:"add_core_of,multi"`
+	}
+	if _, err = EncodeFile(file, &invalid{Cores: "FRA"}); err == nil {
+		t.Fatal("expect patch error")
+	}
+	if _, err = EncodeFile(nil, &invalid{Cores: "FRA"}); err == nil {
+		t.Fatal("expect marshal error")
+	}
+}
@@ -0,0 +1,46 @@
+package pdx
+
+import (
+	"io"
+	"strings"
+)
+
+// Fprint 将节点输出到w，未修改过的节点会按原样输出
+func Fprint(w io.Writer, node Node) error {
+	var buf strings.Builder
+	printNode(&buf, node)
+	_, err := io.WriteString(w, buf.String())
+	return err
+}
+
+// Encode 将节点编码为脚本文本
+func Encode(node Node) string {
+	var buf strings.Builder
+	printNode(&buf, node)
+	return buf.String()
+}
+
+func (f *File) Encode() string {
+	return Encode(f)
+}
+
+func printNode(buf *strings.Builder, node Node) {
+	var prev *Token
+	node.tokens(func(tok *Token) bool {
+		if prev != nil && tok.Kind != TokenEOF {
+			switch {
+			// 行尾注释之后必须换行
+			case strings.ContainsRune(prev.Trailing, '#') && !strings.HasPrefix(tok.Leading, "\n") && !strings.HasPrefix(tok.Leading, "\r\n"):
+				buf.WriteByte('\n')
+			// 两个相邻的裸字之间必须有空白
+			case prev.Kind == TokenWord && tok.Kind == TokenWord && prev.Trailing == "" && tok.Leading == "":
+				buf.WriteByte(' ')
+			}
+		}
+		buf.WriteString(tok.Leading)
+		buf.WriteString(tok.Text)
+		buf.WriteString(tok.Trailing)
+		prev = tok
+		return true
+	})
+}
@@ -0,0 +1,77 @@
+package pdx
+
+import (
+	"testing"
+)
+
+func TestEncodeRoundTrip(t *testing.T) {
+	for _, src := range []string{
+		testSource,
+		"",
+		"\n\n# only comment",
+		"a=b c={1 2 3}#tail",
+		"state={\r\n\tid=1 # crlf\r\n\tprovinces={ 1 2 }\r\n}\r\n",
+		"x = { y = hsv { 0.5 0.2 0.9 } z >= 3 \"quoted key\" = \"v\" }\n\n",
+	} {
+		file, err := Parse("test.txt", []byte(src))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res := file.Encode(); res != src {
+			t.Fatalf("round trip failed:\nexpect: %q\nactual: %q", src, res)
+		}
+	}
+}
+
+func TestEdit(t *testing.T) {
+	src := "state = {\n\tid = 1\n\thistory = {\n\t\towner = FRA # owner\n\t\tadd_core_of = FRA\n\t\tadd_core_of = GER\n\t}\n\tprovinces = { 1 2 }\n}\n"
+	expect := "state = {\n\tid = 1\n\thistory = {\n\t\towner = GER # owner\n\t\tadd_core_of = FRA\n\t\tadd_claim_by = ITA\n\t}\n\tprovinces = { 1 2 3 }\n\tmanpower = 100\n}\n"
+
+	file, err := Parse("test.txt", []byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	state := file.Body.Get("state").Value.(*Block)
+	history := state.Body.Get("history").Value.(*Block)
+	history.Body.Get("owner").Value.(*Scalar).SetValue("GER")
+	history.Remove(history.Body.GetAll("add_core_of")[1])
+	history.Append(NewAssignment("add_claim_by", NewWord("ITA")))
+	state.Body.Get("provinces").Value.(*Block).Append(NewItem(NewInt(3)))
+	state.Set("manpower", NewInt(100))
+	if res := file.Encode(); res != expect {
+		t.Fatalf("edit failed:\nexpect: %q\nactual: %q", expect, res)
+	}
+}
+
+func TestReformat(t *testing.T) {
+	src := "a={b=c # tail\nd={1 2}\n# lead\ne={f=yes} color=rgb{1 2 3}}"
+	expect := "a = {\n\tb = c # tail\n\td = { 1 2 }\n\t# lead\n\te = {\n\t\tf = yes\n\t}\n\tcolor = rgb { 1 2 3 }\n}\n"
+
+	file, err := Parse("test.txt", []byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	Reformat(file, 0)
+	if res := file.Encode(); res != expect {
+		t.Fatalf("reformat failed:\nexpect: %q\nactual: %q", expect, res)
+	}
+
+	// 带标签但含有赋值的块按普通的块排版
+	file, err = Parse("test.txt", []byte("x = foo { a = b } y = bar { z = { } }"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	Reformat(file, 0)
+	if res, expect := file.Encode(), "x = foo {\n\ta = b\n}\ny = bar {\n\tz = { }\n}\n"; res != expect {
+		t.Fatalf("reformat failed:\nexpect: %q\nactual: %q", expect, res)
+	}
+
+	block := NewBlock(NewAssignment("owner", NewWord("GER")), NewAssignment("add_core_of", NewWord("GER")))
+	if res := Encode(block); res != "{ owner = GER add_core_of = GER }" {
+		t.Fatalf("unexpected block %q", res)
+	}
+	Reformat(block, 1)
+	if res := Encode(block); res != "{\n\t\towner = GER\n\t\tadd_core_of = GER\n\t}" {
+		t.Fatalf("unexpected block %q", res)
+	}
+}
@@ -0,0 +1,98 @@
+package pdx
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Pos 源码位置，Line与Column从1开始，Column按字节计算
+type Pos struct {
+	Offset int
+	Line   int
+	Column int
+}
+
+func (p Pos) String() string {
+	return fmt.Sprintf("%d:%d", p.Line, p.Column)
+}
+
+func (p Pos) IsValid() bool {
+	return p.Line > 0
+}
+
+type TokenKind uint8
+
+const (
+	TokenEOF TokenKind = iota
+	TokenWord
+	TokenString
+	TokenAssign
+	TokenEqual
+	TokenNotEqual
+	TokenLess
+	TokenLessEqual
+	TokenGreater
+	TokenGreaterEqual
+	TokenLBrace
+	TokenRBrace
+)
+
+var tokenKindNames = [...]string{
+	TokenEOF:          "EOF",
+	TokenWord:         "word",
+	TokenString:       "string",
+	TokenAssign:       "=",
+	TokenEqual:        "==",
+	TokenNotEqual:     "!=",
+	TokenLess:         "<",
+	TokenLessEqual:    "<=",
+	TokenGreater:      ">",
+	TokenGreaterEqual: ">=",
+	TokenLBrace:       "{",
+	TokenRBrace:       "}",
+}
+
+func (k TokenKind) String() string {
+	if int(k) < len(tokenKindNames) {
+		return tokenKindNames[k]
+	}
+	return fmt.Sprintf("TokenKind(%d)", k)
+}
+
+func (k TokenKind) IsOperator() bool {
+	return k >= TokenAssign && k <= TokenGreaterEqual
+}
+
+// Token 词法单元
+// Leading为该单元之前的空白与注释（包含换行），Trailing为该单元之后同一行内的空白与注释（不包含换行），
+// 依次输出 Leading+Text+Trailing 即可还原源码
+type Token struct {
+	Kind     TokenKind
+	Text     string
+	Pos      Pos
+	Leading  string
+	Trailing string
+}
+
+// End 返回该单元文本结束后的位置
+func (t *Token) End() Pos {
+	pos := t.Pos
+	pos.Offset += len(t.Text)
+	pos.Column += len(t.Text)
+	return pos
+}
+
+// Comments 返回该单元前后的注释（不含#）
+func (t *Token) Comments() []string {
+	return append(triviaComments(t.Leading), triviaComments(t.Trailing)...)
+}
+
+func triviaComments(trivia string) []string {
+	var comments []string
+	for _, line := range strings.Split(trivia, "\n") {
+		if i := strings.IndexByte(line, '#'); i >= 0 {
+			comments = append(comments, strings.TrimRight(line[i+1:], "\r"))
+		}
+	}
+	return comments
+}
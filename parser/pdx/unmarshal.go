@@ -0,0 +1,308 @@
+package pdx
+
+import (
+	"errors"
+	"fmt"
+	"image/color"
+	"reflect"
+	"strconv"
+
+	"github.com/kkkunny/TEW-hoi4/util"
+)
+
+// Unmarshaler 自定义解码
+type Unmarshaler interface {
+	UnmarshalPDX(value Value) error
+}
+
+var (
+	unmarshalerType = reflect.TypeFor[Unmarshaler]()
+	statementsType  = reflect.TypeFor[Statements]()
+	colorType       = reflect.TypeFor[color.Color]()
+)
+
+// Unmarshal 将脚本解码到v中，v必须是指向结构体或map的指针
+func Unmarshal(data []byte, v any) error {
+	file, err := Parse("", data)
+	if err != nil {
+		return err
+	}
+	return UnmarshalNode(file, v)
+}
+
+// UnmarshalFile 读取脚本文件并解码到v中
+func UnmarshalFile(path string, v any) error {
+	file, err := ParseFile(path)
+	if err != nil {
+		return err
+	}
+	return UnmarshalNode(file, v)
+}
+
+// UnmarshalNode 将语法树节点解码到v中
+func UnmarshalNode(node Node, v any) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return errors.New("pdx: unmarshal target must be a non-nil pointer")
+	}
+	d := &decoder{}
+	switch n := node.(type) {
+	case *File:
+		d.filename = n.Filename
+		return d.decodeStatements(n.Body, n.Pos(), rv.Elem())
+	case *Assignment:
+		return d.decodeValue(n.Value, rv.Elem(), nil)
+	case *Item:
+		return d.decodeValue(n.Value, rv.Elem(), nil)
+	case Value:
+		return d.decodeValue(n, rv.Elem(), nil)
+	default:
+		return fmt.Errorf("pdx: cannot unmarshal %T", node)
+	}
+}
+
+type decoder struct {
+	filename string
+}
+
+func (d *decoder) errorf(pos Pos, format string, args ...any) error {
+	return &Error{Filename: d.filename, Pos: pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+func (d *decoder) decodeStatements(stmts Statements, pos Pos, v reflect.Value) error {
+	switch v.Kind() {
+	case reflect.Struct:
+		return d.decodeStruct(stmts, v)
+	case reflect.Map:
+		return d.decodeMap(stmts, v)
+	case reflect.Pointer:
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return d.decodeStatements(stmts, pos, v.Elem())
+	default:
+		if v.Type() == statementsType {
+			v.Set(reflect.ValueOf(stmts))
+			return nil
+		}
+		return d.errorf(pos, "cannot decode block into %s", v.Type())
+	}
+}
+
+func (d *decoder) decodeStruct(stmts Statements, v reflect.Value) error {
+	fields := structFields(v.Type())
+	byName := make(map[string]*fieldInfo, len(fields))
+	var remain *fieldInfo
+	for _, f := range fields {
+		if f.remain {
+			remain = f
+			continue
+		}
+		byName[f.name] = f
+		if f.multi {
+			v.FieldByIndex(f.index).SetZero()
+		}
+	}
+
+	var rest Statements
+	for _, stmt := range stmts {
+		a, ok := stmt.(*Assignment)
+		if !ok {
+			rest = append(rest, stmt)
+			continue
+		}
+		f, ok := byName[a.Name()]
+		if !ok {
+			rest = append(rest, stmt)
+			continue
+		}
+		field := v.FieldByIndex(f.index)
+		if !f.multi {
+			if err := d.decodeValue(a.Value, field, f); err != nil {
+				return err
+			}
+			continue
+		}
+		if field.Kind() != reflect.Slice {
+			return d.errorf(a.Pos(), "field with `multi` option must be a slice, but got %s", field.Type())
+		}
+		elem := reflect.New(field.Type().Elem()).Elem()
+		if err := d.decodeValue(a.Value, elem, f); err != nil {
+			return err
+		}
+		field.Set(reflect.Append(field, elem))
+	}
+	if remain != nil {
+		field := v.FieldByIndex(remain.index)
+		if field.Type() != statementsType {
+			return fmt.Errorf("pdx: field with `remain` option must be pdx.Statements, but got %s", field.Type())
+		}
+		field.Set(reflect.ValueOf(rest))
+	}
+	return nil
+}
+
+func (d *decoder) decodeMap(stmts Statements, v reflect.Value) error {
+	if v.IsNil() {
+		v.Set(reflect.MakeMap(v.Type()))
+	}
+	for _, stmt := range stmts {
+		a, ok := stmt.(*Assignment)
+		if !ok {
+			return d.errorf(stmt.Pos(), "expect assignment in map block")
+		}
+		key := reflect.New(v.Type().Key()).Elem()
+		if err := d.decodeValue(a.Key, key, nil); err != nil {
+			return err
+		}
+		elem := reflect.New(v.Type().Elem()).Elem()
+		if old := v.MapIndex(key); old.IsValid() {
+			elem.Set(old)
+		}
+		if err := d.decodeValue(a.Value, elem, nil); err != nil {
+			return err
+		}
+		v.SetMapIndex(key, elem)
+	}
+	return nil
+}
+
+func (d *decoder) decodeValue(value Value, v reflect.Value, info *fieldInfo) error {
+	if v.CanAddr() && v.Addr().Type().Implements(unmarshalerType) {
+		return v.Addr().Interface().(Unmarshaler).UnmarshalPDX(value)
+	}
+	if reflect.TypeOf(value).AssignableTo(v.Type()) && (v.Kind() == reflect.Interface || v.Kind() == reflect.Pointer) && v.Type() != colorType {
+		v.Set(reflect.ValueOf(value))
+		return nil
+	}
+	if isOptional(v.Type()) {
+		elem := reflect.New(optionalElem(v.Type())).Elem()
+		if err := d.decodeValue(value, elem, info); err != nil {
+			return err
+		}
+		return setOptional(v, elem)
+	}
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return d.decodeValue(value, v.Elem(), info)
+	}
+	if v.Type() == colorType || (info != nil && info.colorMode != "") {
+		return d.decodeColor(value, v)
+	}
+
+	if block, ok := value.(*Block); ok {
+		switch v.Kind() {
+		case reflect.Slice:
+			items, err := d.listItems(block)
+			if err != nil {
+				return err
+			}
+			v.Set(reflect.MakeSlice(v.Type(), len(items), len(items)))
+			for i, item := range items {
+				if err = d.decodeValue(item, v.Index(i), nil); err != nil {
+					return err
+				}
+			}
+			return nil
+		case reflect.Array:
+			items, err := d.listItems(block)
+			if err != nil {
+				return err
+			}
+			if len(items) != v.Len() {
+				return d.errorf(block.Pos(), "expect %d values, but got %d", v.Len(), len(items))
+			}
+			for i, item := range items {
+				if err = d.decodeValue(item, v.Index(i), nil); err != nil {
+					return err
+				}
+			}
+			return nil
+		default:
+			return d.decodeStatements(block.Body, block.Pos(), v)
+		}
+	}
+
+	scalar := value.(*Scalar)
+	var err error
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(scalar.Value())
+	case reflect.Bool:
+		var b bool
+		if b, err = scalar.Bool(); err == nil {
+			v.SetBool(b)
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		var i int64
+		if i, err = strconv.ParseInt(scalar.Value(), 10, v.Type().Bits()); err == nil {
+			v.SetInt(i)
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		var u uint64
+		if u, err = strconv.ParseUint(scalar.Value(), 10, v.Type().Bits()); err == nil {
+			v.SetUint(u)
+		}
+	case reflect.Float32, reflect.Float64:
+		var f float64
+		if f, err = strconv.ParseFloat(scalar.Value(), v.Type().Bits()); err == nil {
+			v.SetFloat(f)
+		}
+	default:
+		return d.errorf(scalar.Pos(), "cannot decode `%s` into %s", scalar.Tok.Text, v.Type())
+	}
+	if err != nil {
+		return d.errorf(scalar.Pos(), "cannot decode `%s` into %s", scalar.Tok.Text, v.Type())
+	}
+	return nil
+}
+
+func (d *decoder) listItems(block *Block) ([]Value, error) {
+	if !block.IsList() {
+		return nil, d.errorf(block.Pos(), "expect a list")
+	}
+	return block.Body.Items(), nil
+}
+
+func (d *decoder) decodeColor(value Value, v reflect.Value) error {
+	block, ok := value.(*Block)
+	if !ok {
+		return d.errorf(value.Pos(), "expect a color")
+	}
+	items, err := d.listItems(block)
+	if err != nil {
+		return err
+	}
+	if len(items) != 3 {
+		return d.errorf(block.Pos(), "expect 3 color values, but got %d", len(items))
+	}
+	var vs [3]float64
+	for i, item := range items {
+		s, ok := item.(*Scalar)
+		if !ok {
+			return d.errorf(item.Pos(), "expect a number")
+		}
+		if vs[i], err = s.Float(); err != nil {
+			return d.errorf(item.Pos(), "expect a number, but got `%s`", s.Tok.Text)
+		}
+	}
+	clr, err := util.NewColorByMode(block.TagName(), vs[0], vs[1], vs[2])
+	if err != nil {
+		return d.errorf(block.Pos(), "%s", err.Error())
+	}
+
+	switch {
+	case v.Type() == colorType:
+		v.Set(reflect.ValueOf(clr))
+	case v.Type() == reflect.TypeFor[[3]uint8]():
+		r, g, b := util.GetRGB(clr)
+		v.Set(reflect.ValueOf([3]uint8{r, g, b}))
+	case reflect.TypeOf(clr).ConvertibleTo(v.Type()):
+		v.Set(reflect.ValueOf(clr).Convert(v.Type()))
+	default:
+		return d.errorf(block.Pos(), "cannot decode color into %s", v.Type())
+	}
+	return nil
+}
@@ -0,0 +1,153 @@
+package sdk
+
+import (
+	"fmt"
+	"image/color"
+	"slices"
+	"strings"
+
+	"github.com/kkkunny/stl/container/optional"
+
+	"github.com/kkkunny/TEW-hoi4/config"
+	"github.com/kkkunny/TEW-hoi4/util"
+)
+
+// 分配国家颜色时要求的最小CIEDE2000距离
+const (
+	minCountryColorDistance      = 0.05
+	neighborCountryColorDistance = 0.15
+)
+
+// 修正相邻国家颜色时的最大轮数
+const maxColorRepairRounds = 10
+
+// allocateCountryColors 为没有颜色的国家分配颜色，返回生成的颜色数量
+// 按图着色的方式，先为已着色邻国最多的国家分配，之后反复为与邻国颜色过近的国家重新分配
+// countries.json中已有的颜色保持不变
+func allocateCountryColors(opts Options) (int, error) {
+	neighbors, err := countryNeighbors(opts)
+	if err != nil {
+		return 0, err
+	}
+
+	allocator := util.NewColorAllocator(minCountryColorDistance, neighborCountryColorDistance)
+	fixed := make(map[string]bool, len(config.CountryList))
+	var pending []*config.Country
+	for _, c := range config.CountryList {
+		if c.Color.IsSome() {
+			fixed[c.ID] = true
+			allocator.Add(countryColor(c))
+		} else {
+			pending = append(pending, c)
+		}
+	}
+	generated := len(pending)
+
+	neighborColors := func(c *config.Country) []color.Color {
+		var colors []color.Color
+		for _, n := range neighbors[c.ID] {
+			if nc, ok := config.Countries[n]; ok && nc.Color.IsSome() {
+				colors = append(colors, countryColor(nc))
+			}
+		}
+		return colors
+	}
+	// crowded 最近一次分配时无法满足距离要求的国家
+	crowded := make(map[string]bool)
+	allocate := func(c *config.Country, round int) {
+		clr, ok := allocator.Allocate(newRand(opts.Seed, c.ID, "color", fmt.Sprint(round)), neighborColors(c))
+		r, g, b := util.GetRGB(clr)
+		c.Color = optional.Some([3]uint8{r, g, b})
+		crowded[c.ID] = !ok
+		opts.debugf("生成国家%s的颜色 rgb { %d %d %d }", c.ID, r, g, b)
+	}
+
+	// 每次选择已着色邻国最多的国家，相同时选择邻国最多的
+	for len(pending) != 0 {
+		best := 0
+		for i, c := range pending[1:] {
+			if compareColoringOrder(c, pending[best], neighbors) < 0 {
+				best = i + 1
+			}
+		}
+		allocate(pending[best], 0)
+		pending = slices.Delete(pending, best, best+1)
+	}
+
+	for round := 1; round <= maxColorRepairRounds; round++ {
+		conflicts := colorConflicts(neighbors)
+		var repaired bool
+		for _, conflict := range conflicts {
+			for _, id := range conflict {
+				if fixed[id] {
+					continue
+				}
+				c := config.Countries[id]
+				allocator.Remove(countryColor(c))
+				allocate(c, round)
+				repaired = true
+				break
+			}
+		}
+		if !repaired {
+			break
+		}
+	}
+
+	var crowdedIDs []string
+	for _, c := range config.CountryList {
+		if crowded[c.ID] && !slices.Contains(crowdedIDs, c.ID) {
+			crowdedIDs = append(crowdedIDs, c.ID)
+		}
+	}
+	if len(crowdedIDs) != 0 {
+		opts.logf("警告：以下%d个国家无法分配与其他国家足够不同的颜色：%s", len(crowdedIDs), strings.Join(crowdedIDs, " "))
+	}
+	if conflicts := colorConflicts(neighbors); len(conflicts) != 0 {
+		strs := make([]string, len(conflicts))
+		for i, conflict := range conflicts {
+			strs[i] = conflict[0] + "-" + conflict[1]
+		}
+		opts.logf("警告：以下%d对相邻国家的颜色过于接近：%s", len(conflicts), strings.Join(strs, " "))
+	}
+	return generated, nil
+}
+
+// compareColoringOrder 着色顺序，已着色的邻国越多越靠前
+func compareColoringOrder(a, b *config.Country, neighbors map[string][]string) int {
+	colored := func(c *config.Country) int {
+		var n int
+		for _, id := range neighbors[c.ID] {
+			if nc, ok := config.Countries[id]; ok && nc.Color.IsSome() {
+				n++
+			}
+		}
+		return n
+	}
+	if d := colored(b) - colored(a); d != 0 {
+		return d
+	}
+	return len(neighbors[b.ID]) - len(neighbors[a.ID])
+}
+
+// colorConflicts 返回颜色距离小于要求的相邻国家
+func colorConflicts(neighbors map[string][]string) [][2]string {
+	var conflicts [][2]string
+	for _, c := range config.CountryList {
+		for _, n := range neighbors[c.ID] {
+			nc, ok := config.Countries[n]
+			if !ok || n < c.ID || c.Color.IsNone() || nc.Color.IsNone() {
+				continue
+			}
+			if util.ColorDistance(countryColor(c), countryColor(nc)) < neighborCountryColorDistance {
+				conflicts = append(conflicts, [2]string{c.ID, n})
+			}
+		}
+	}
+	return conflicts
+}
+
+func countryColor(c *config.Country) color.Color {
+	rgb := c.Color.MustValue()
+	return util.NewRGB(rgb[0], rgb[1], rgb[2])
+}
@@ -2,23 +2,11 @@ package sdk
 
 import (
 	"bytes"
-	"errors"
 	"fmt"
 	"image/color"
-	"math/rand/v2"
-	"os"
 	"path/filepath"
-	"strings"
 
 	stlbasic "github.com/kkkunny/stl/basic"
-	"github.com/kkkunny/stl/container/hashset"
-	"github.com/kkkunny/stl/container/linkedhashmap"
-	stlmaps "github.com/kkkunny/stl/container/maps"
-	"github.com/kkkunny/stl/container/optional"
-	"github.com/kkkunny/stl/container/pair"
-	stlslices "github.com/kkkunny/stl/container/slices"
-	"github.com/lucasb-eyer/go-colorful"
-	"golang.org/x/exp/maps"
 
 	"github.com/kkkunny/TEW-hoi4/config"
 	"github.com/kkkunny/TEW-hoi4/parser/common"
@@ -26,12 +14,28 @@ import (
 	"github.com/kkkunny/TEW-hoi4/util"
 )
 
-func RefreshCountries() error {
-	modPath := config.TEWRootPath
+// RefreshCountries 按countries.json重新生成国家相关文件，所有文件生成完毕后才统一写入
+func RefreshCountries(opts Options) error {
+	modPath := opts.ModPath
+	errs, warns := validateCountries(opts, config.CountryList)
+	for _, err := range warns {
+		opts.logf("warning: %s", err.Error())
+	}
+	if len(errs) != 0 {
+		for _, err := range errs {
+			opts.logf("%s", err.Error())
+		}
+		return fmt.Errorf("found %d country problems, fix %s before generating", len(errs), config.CountriesSource())
+	}
+	opts.beginFiles("countries")
+
+	// 所有文件都按countries.json中的顺序输出，使多次生成的结果只在配置变化时不同
+	countries := config.CountryList
+	hierarchy := NewCountryHierarchy(countries)
 
-	fmt.Println("生成国家tag文件中...")
+	opts.logf("生成国家tag文件中...")
 	var countryTagBuffer bytes.Buffer
-	for _, c := range config.Countries {
+	for _, c := range countries {
 		ct := common.CountryTag{
 			ID:         c.ID,
 			DefinePath: "countries/" + c.Region + ".txt",
@@ -39,354 +43,203 @@ func RefreshCountries() error {
 		countryTagBuffer.WriteString(ct.Encode())
 		countryTagBuffer.WriteString("\n")
 	}
-	err := os.WriteFile(filepath.Join(modPath, "common", "country_tags", "tew_auto_generate.txt"), countryTagBuffer.Bytes(), 0666)
+	err := opts.writeFile(filepath.Join(modPath, "common", "country_tags", "tew_auto_generate.txt"), countryTagBuffer.Bytes(), false)
 	if err != nil {
 		return err
 	}
-	fmt.Println("生成国家tag文件成功！")
+	opts.logf("生成国家tag文件成功！")
 
-	fmt.Println("生成国家名字文件中...")
-	var countryLocBuffer bytes.Buffer
-	countryLocBuffer.WriteString("l_simp_chinese:\n")
-	for _, c := range config.Countries {
-		loc := &localisation.Localisation{
-			Key:   c.ID,
-			Index: optional.Some(0),
-			Value: c.Name,
-		}
-		countryLocBuffer.WriteByte(' ')
-		countryLocBuffer.WriteString(loc.Encode())
-		countryLocBuffer.WriteString("\n")
-		loc.Key = c.ID + "_DEF"
-		countryLocBuffer.WriteByte(' ')
-		countryLocBuffer.WriteString(loc.Encode())
-		countryLocBuffer.WriteString("\n")
-		loc.Key = c.ID + "_ADJ"
-		countryLocBuffer.WriteByte(' ')
-		countryLocBuffer.WriteString(loc.Encode())
-		countryLocBuffer.WriteString("\n\n")
-	}
-	err = util.WriteFileWithBOM(filepath.Join(modPath, "localisation", "simp_chinese", "tew_countries_auto_generate_l_simp_chinese.yml"), countryLocBuffer.Bytes())
+	opts.logf("生成国家名字文件中...")
+	err = opts.writeTemplate(filepath.Join(modPath, "localisation", "simp_chinese", "tew_countries_auto_generate_l_simp_chinese.yml"), "countries_l_simp_chinese.yml.tmpl", map[string]any{
+		"Countries": countries,
+	}, true)
 	if err != nil {
 		return err
 	}
-	fmt.Println("生成国家名字文件成功！")
+	opts.logf("生成国家名字文件成功！")
 
-	fmt.Println("生成国家颜色文件中...")
-	existColors := hashset.NewHashSetWith(stlslices.FlatMap(maps.Values(config.Countries), func(_ int, c *config.Country) [][3]uint8 {
-		if c.Color.IsNone() {
-			return nil
-		}
-		return [][3]uint8{{c.Color.MustValue()[0], c.Color.MustValue()[1], c.Color.MustValue()[2]}}
-	})...)
-	countryColors := make(map[string]*common.CountryColor, len(config.Countries))
-	for _, c := range config.Countries {
-		if c.Color.IsNone() {
-			var cc color.Color
-			for i := 0; i < 51; i++ {
-				cc = colorful.Hsv(float64(rand.N(361)), float64(rand.N(71))/100, float64(50+rand.N(51))/100)
-				r, g, b := util.GetRGB(cc)
-				if !existColors.Contain([3]uint8{r, g, b}) {
-					break
-				}
-				if i == 50 {
-					return errors.New("can generate more color")
-				}
-			}
-			r, g, b := util.GetRGB(cc)
-			c.Color = optional.Some([3]uint8{r, g, b})
-		}
-		countryColors[c.ID] = &common.CountryColor{
+	opts.logf("生成国家颜色文件中...")
+	generatedColors, err := allocateCountryColors(opts)
+	if err != nil {
+		return err
+	}
+	var countryColorBuffer bytes.Buffer
+	for _, c := range countries {
+		cc := common.CountryColor{
 			Country: c.ID,
 			Color:   util.NewRGB(c.Color.MustValue()[0], c.Color.MustValue()[1], c.Color.MustValue()[2]),
 			ColorUI: util.NewRGB(c.Color.MustValue()[0], c.Color.MustValue()[1], c.Color.MustValue()[2]),
 		}
-	}
-	var countryColorBuffer bytes.Buffer
-	for _, cc := range countryColors {
 		countryColorBuffer.WriteString(cc.Encode())
 		countryColorBuffer.WriteString("\n")
 	}
-	err = os.WriteFile(filepath.Join(modPath, "common", "countries", "colors.txt"), countryColorBuffer.Bytes(), 0666)
+	err = opts.writeFile(filepath.Join(modPath, "common", "countries", "colors.txt"), countryColorBuffer.Bytes(), false)
 	if err != nil {
 		return err
 	}
-	fmt.Println("生成国家颜色文件成功！")
+	opts.logf("生成国家颜色文件成功！")
 
-	fmt.Println("生成不同国家类型颜色文件中...")
-	cosmeticCountryColors := make(map[string]*common.CountryColor, len(config.Countries))
-	countryTypes := linkedhashmap.NewLinkedHashMapWith[string, [3]uint8](
-		"anarchism", [3]uint8{255, 107, 0},
-		"communism", [3]uint8{255, 0, 0},
-		"democratic", [3]uint8{0, 0, 255},
-		"conservatism", [3]uint8{0, 255, 255},
-		"feudalism", [3]uint8{192, 192, 192},
-		"dictatorship", [3]uint8{255, 255, 0},
-		"fascism", [3]uint8{102, 51, 0},
-	)
-	for _, c := range config.Countries {
-		for iter := countryTypes.Iterator(); iter.Next(); {
-			tc := iter.Value().Second
-			id := fmt.Sprintf("%s_type_%s", c.ID, iter.Value().First)
-			cc := util.AlphaBlendColor(
-				util.NewRGB(c.Color.MustValue()[0], c.Color.MustValue()[1], c.Color.MustValue()[2]),
-				util.NewRGB(tc[0], tc[1], tc[2]),
-				float32(rand.N(4)+4)/10,
-			)
-			// cc := color.GetRGBA{R: (c.Color.MustValue()[0] + tc[0]) / 2, G: (c.Color.MustValue()[1] + tc[1]) / 2, B: (c.Color.MustValue()[2] + tc[2]) / 2}
-			cosmeticCountryColors[id] = &common.CountryColor{
+	if generatedColors != 0 {
+		err = saveCountries(opts, generatedColors)
+		if err != nil {
+			return err
+		}
+	}
+
+	opts.logf("生成不同国家类型颜色文件中...")
+	var cosmeticCountryColorBuffer bytes.Buffer
+	for _, c := range countries {
+		for _, ct := range config.CountryTypes {
+			id := ct.CosmeticTag(c.ID)
+			var cc color.Color
+			if override := c.TypeOverride(ct.ID); override.Color.IsSome() {
+				rgb := override.Color.MustValue()
+				cc = util.NewRGB(rgb[0], rgb[1], rgb[2])
+			} else {
+				cc = util.AlphaBlendColor(
+					util.NewRGB(c.Color.MustValue()[0], c.Color.MustValue()[1], c.Color.MustValue()[2]),
+					util.NewRGB(ct.Color[0], ct.Color[1], ct.Color[2]),
+					float32(newRand(opts.Seed, id, "blend").IntN(4)+4)/10,
+				)
+			}
+			ccc := common.CountryColor{
 				Country: id,
 				Color:   cc,
 				ColorUI: cc,
 			}
+			cosmeticCountryColorBuffer.WriteString(ccc.Encode())
+			cosmeticCountryColorBuffer.WriteString("\n")
 		}
 	}
-	var cosmeticCountryColorBuffer bytes.Buffer
-	for _, ccc := range cosmeticCountryColors {
-		cosmeticCountryColorBuffer.WriteString(ccc.Encode())
-		cosmeticCountryColorBuffer.WriteString("\n")
-	}
-	err = os.WriteFile(filepath.Join(modPath, "common", "countries", "cosmetic.txt"), cosmeticCountryColorBuffer.Bytes(), 0666)
+	err = opts.writeFile(filepath.Join(modPath, "common", "countries", "cosmetic.txt"), cosmeticCountryColorBuffer.Bytes(), false)
 	if err != nil {
 		return err
 	}
-	fmt.Println("生成不同国家类型颜色文件成功！")
+	opts.logf("生成不同国家类型颜色文件成功！")
 
-	locs, err := localisation.ParseChineseLocalisationDir(config.TEWRootPath)
+	locs, err := localisation.ParseChineseLocalisationDir(modPath)
 	if err != nil {
 		return err
 	}
 
-	fmt.Println("生成国家不同类型名字文件中...")
-	countryTypeNameFormat := map[string]string{
-		"anarchism":    "%s公社",
-		"communism":    "%s社会主义共和国",
-		"democratic":   "%s共和国",
-		"conservatism": "%s王国",
-		"feudalism":    "%s王国",
-		"dictatorship": "%s国",
-		"fascism":      "大%s帝国",
-	}
-	var cosmeticCountryLocBuffer bytes.Buffer
-	cosmeticCountryLocBuffer.WriteString("l_simp_chinese:\n")
-	for _, c := range config.Countries {
-		for iter := countryTypes.Iterator(); iter.Next(); {
-			countryID := fmt.Sprintf("%s_type_%s", c.ID, iter.Value().First)
-			loc := &localisation.Localisation{
-				Key:   countryID,
-				Index: optional.Some(0),
-				Value: stlbasic.Ternary(stlmaps.ContainKey(locs, countryID), locs[countryID].Value, fmt.Sprintf(countryTypeNameFormat[iter.Value().First], c.Name)),
+	opts.logf("生成国家不同类型名字文件中...")
+	type typeLoc struct {
+		Tag, Name, DEF string
+	}
+	type countryTypeLocs struct {
+		ID    string
+		Types []typeLoc
+	}
+	typeLocs := make([]countryTypeLocs, len(countries))
+	for i, c := range countries {
+		typeLocs[i].ID = c.ID
+		for _, ct := range config.CountryTypes {
+			tag := ct.CosmeticTag(c.ID)
+			override := c.TypeOverride(ct.ID)
+			name := fmt.Sprintf(ct.NameFormat, c.Name)
+			if override.Name != "" {
+				name = override.Name
+			} else if existLoc, ok := locs[tag]; ok {
+				name = existLoc.Value
 			}
-			cosmeticCountryLocBuffer.WriteByte(' ')
-			cosmeticCountryLocBuffer.WriteString(loc.Encode())
-			cosmeticCountryLocBuffer.WriteString("\n")
-			loc.Key = countryID + "_DEF"
-			cosmeticCountryLocBuffer.WriteByte(' ')
-			cosmeticCountryLocBuffer.WriteString(loc.Encode())
-			cosmeticCountryLocBuffer.WriteString("\n")
+			typeLocs[i].Types = append(typeLocs[i].Types, typeLoc{
+				Tag:  tag,
+				Name: name,
+				DEF:  stlbasic.Ternary(override.DEF != "", override.DEF, name),
+			})
 		}
-		cosmeticCountryLocBuffer.WriteString("\n")
 	}
-	err = util.WriteFileWithBOM(filepath.Join(modPath, "localisation", "simp_chinese", "tew_country_types_auto_generate_l_simp_chinese.yml"), cosmeticCountryLocBuffer.Bytes())
+	err = opts.writeTemplate(filepath.Join(modPath, "localisation", "simp_chinese", "tew_country_types_auto_generate_l_simp_chinese.yml"), "country_types_l_simp_chinese.yml.tmpl", map[string]any{
+		"Countries": typeLocs,
+	}, true)
 	if err != nil {
 		return err
 	}
-	fmt.Println("生成国家不同类型名字文件成功！")
+	opts.logf("生成国家不同类型名字文件成功！")
 
-	fmt.Println("生成国家不同傀儡类型名字文件中...")
-	autonomyTypeNameFormat := map[string]string{
-		"dominion": "$OVERLORDADJ$属$NONIDEOLOGYADJ$自治领",
-		"colony":   "$OVERLORDADJ$属$NONIDEOLOGYADJ$殖民政府",
-		"puppet":   "$OVERLORDADJ$属$NONIDEOLOGYADJ$",
-		"union":    "$OVERLORDADJ$-$NONIDEOLOGYADJ$邦",
-		"division": "$OVERLORDADJ$-$NONIDEOLOGYADJ$军阀",
-	}
-	var autonomyCountryLocBuffer bytes.Buffer
-	autonomyCountryLocBuffer.WriteString("l_simp_chinese:\n")
-	for _, c := range config.Countries {
-		for at, name := range autonomyTypeNameFormat {
-			countryID := fmt.Sprintf("%s_tew_autonomy_%s", c.ID, at)
-			loc := &localisation.Localisation{
-				Key:   countryID,
-				Index: optional.Some(0),
-				Value: name,
+	opts.logf("复制国家不同类型旗帜中...")
+	for _, c := range config.CountryList {
+		for _, ct := range config.CountryTypes {
+			if flag := c.TypeOverride(ct.ID).Flag; flag != "" {
+				err = copyFlag(opts, flag, ct.CosmeticTag(c.ID))
+				if err != nil {
+					return fmt.Errorf("country `%s` type `%s`: %s", c.ID, ct.ID, err.Error())
+				}
 			}
-			autonomyCountryLocBuffer.WriteByte(' ')
-			autonomyCountryLocBuffer.WriteString(loc.Encode())
-			autonomyCountryLocBuffer.WriteString("\n")
-			loc.Key = countryID + "_DEF"
-			autonomyCountryLocBuffer.WriteByte(' ')
-			autonomyCountryLocBuffer.WriteString(loc.Encode())
-			autonomyCountryLocBuffer.WriteString("\n")
 		}
-		autonomyCountryLocBuffer.WriteString("\n")
 	}
-	err = util.WriteFileWithBOM(filepath.Join(modPath, "localisation", "simp_chinese", "tew_autonomy_name_l_simp_chinese copy.yml"), autonomyCountryLocBuffer.Bytes())
+	opts.logf("复制国家不同类型旗帜成功！")
+
+	opts.logf("生成国家不同傀儡类型名字文件中...")
+	err = opts.writeTemplate(filepath.Join(modPath, "localisation", "simp_chinese", "tew_autonomy_name_l_simp_chinese copy.yml"), "autonomy_l_simp_chinese.yml.tmpl", map[string]any{
+		"Countries": countries,
+	}, true)
 	if err != nil {
 		return err
 	}
-	fmt.Println("生成国家不同傀儡类型名字文件成功！")
-
-	var findSonCountries func(tag string) []*config.Country
-	findSonCountries = func(tag string) []*config.Country {
-		c, ok := config.Countries[tag]
-		if !ok || config.Countries[tag].Sons.IsNone() || stlslices.Empty(config.Countries[tag].Sons.MustValue()) {
-			return nil
-		}
-		return stlslices.RemoveRepeat(stlslices.Filter(stlslices.FlatMap(c.Sons.MustValue(), func(_ int, e string) []*config.Country {
-			return append(findSonCountries(e), config.Countries[e])
-		}), func(_ int, e *config.Country) bool {
-			return e != nil
-		}))
-	}
-
-	canUpgradedCountries := stlslices.ToMap(stlslices.Filter(maps.Values(config.Countries), func(_ int, c *config.Country) bool {
-		return c.Sons.IsSome() && !stlslices.Empty(c.Sons.MustValue())
-	}), func(e *config.Country) (string, *config.Country) {
-		return e.ID, e
-	})
-	sonTag2ParentTags := stlslices.FlatMap(maps.Values(canUpgradedCountries), func(_ int, c *config.Country) []pair.Pair[*config.Country, *config.Country] {
-		return stlslices.Filter(stlslices.FlatMap(c.Sons.MustValue(), func(_ int, sonTag string) []pair.Pair[*config.Country, *config.Country] {
-			return stlslices.Map(append(findSonCountries(sonTag), config.Countries[sonTag]), func(_ int, son *config.Country) pair.Pair[*config.Country, *config.Country] {
-				return pair.NewPair(son, c)
-			})
-		}), func(_ int, e pair.Pair[*config.Country, *config.Country]) bool {
-			return e.First != nil
+	opts.logf("生成国家不同傀儡类型名字文件成功！")
+
+	type upgradeCountry struct {
+		*config.Country
+		Sons         []string
+		UpgradeRatio int64
+		Conflicts    []string
+	}
+	var upgradeCountries []upgradeCountry
+	for _, id := range hierarchy.Formables() {
+		c, _ := hierarchy.Country(id)
+		upgradeCountries = append(upgradeCountries, upgradeCountry{
+			Country:      c,
+			Sons:         hierarchy.Members(id),
+			UpgradeRatio: c.UpgradeRatio.ValueWith(defaultUpgradeRatio),
+			Conflicts:    hierarchy.Conflicts(id),
 		})
-	})
-	sonTag2ParentTagsMap := make(map[string]*hashset.HashSet[string], len(sonTag2ParentTags))
-	for _, p := range sonTag2ParentTags {
-		if sonTag2ParentTagsMap[p.First.ID] == nil {
-			sonTag2ParentTagsMap[p.First.ID] = stlbasic.Ptr(hashset.NewHashSet[string]())
-		}
-		sonTag2ParentTagsMap[p.First.ID].Add(p.Second.ID)
 	}
-
-	fmt.Println("生成国家成立脚本文件中...")
-	var tagScriptedEffectBuffer bytes.Buffer
-	tagScriptedEffectBuffer.WriteString("ideas = {\n\tcountry_tag = {\n\t\tlaw = yes\n\n\t\tcountry_tag_default = {\n\t\t\ton_add = {\n\t\t\t\ttew_update_country_type = yes\n\t\t\t}\n\n\t\t\tai_will_do = { factor = 0 }\n\n\t\t\tcancel_if_invalid = yes\n\t\t\tdefault = yes\n\t\t}")
-	for _, c := range canUpgradedCountries {
-		tagScriptedEffectBuffer.WriteString("\n\n\t\t")
-		tagScriptedEffectBuffer.WriteString("country_tag_")
-		tagScriptedEffectBuffer.WriteString(c.ID)
-		tagScriptedEffectBuffer.WriteString(" = {\n\t\t\tallowed = {\n\t\t\t\tOR = {\n\t\t\t\t\t")
-		tagScriptedEffectBuffer.WriteString(strings.Join(stlslices.Map(findSonCountries(c.ID), func(_ int, e *config.Country) string { return "original_tag = " + e.ID }), "\n\t\t\t\t\t"))
-		tagScriptedEffectBuffer.WriteString(fmt.Sprintf("\n\t\t\t\t}\n\t\t\t}\n\n\t\t\tavailable = {\n\t\t\t\ttew_can_ndependent_diplomacy = yes\n\t\t\t\tNOT = {\n\t\t\t\t\tcountry_exists = %s\n\t\t\t\t\tany_other_country = {\n\t\t\t\t\t\tlimit = { exists = yes }\n\t\t\t\t\t\thas_idea = country_tag_%s\n\t\t\t\t\t}\n\t\t\t\t}\n\t\t\t\t%s = {\n\t\t\t\t\tset_temp_variable = { tew_than_number = %d }\n\t\t\t\t\ttew_self_or_puppet_owns_gte = yes\n\t\t\t\t}\n\t\t\t}\n\n\t\t\ton_add = {\n\t\t\t\ttew_update_country_type = yes\n\t\t\t}\n\n\t\t\tai_will_do = {\n\t\t\t\tfactor = 100", c.ID, c.ID, c.ID, c.UpgradeRatio.ValueWith(70)))
-		sonTags := stlslices.Map(findSonCountries(c.ID), func(_ int, e *config.Country) string { return e.ID })
-		conflictCountries := hashset.NewHashSet[string]()
-		for _, scTag := range sonTags {
-			parents, ok := sonTag2ParentTagsMap[scTag]
-			if !ok {
-				continue
-			}
-			for iter := parents.Iterator(); iter.Next(); {
-				if iter.Value() == c.ID {
-					continue
-				} else if stlslices.Contain(sonTags, iter.Value()) {
-					continue
-				} else if conflictCountries.Contain(iter.Value()) {
-					continue
-				}
-				conflictCountries.Add(iter.Value())
-				tagScriptedEffectBuffer.WriteString(fmt.Sprintf("\n\t\t\t\tmodifier = {\n\t\t\t\t\tfactor = 0\n\t\t\t\t\thas_idea = country_tag_%s\n\t\t\t\t}", iter.Value()))
-			}
-		}
-		tagScriptedEffectBuffer.WriteString("\n\t\t\t}\n\n\t\t\tcancel_if_invalid = yes\n\t\t}")
+	data := map[string]any{
+		"Countries":        countries,
+		"CountryTypes":     config.CountryTypes,
+		"UpgradeCountries": upgradeCountries,
 	}
-	tagScriptedEffectBuffer.WriteString("\n}")
-	err = os.WriteFile(filepath.Join(modPath, "common", "ideas", "tew_attr_country_tag_auto_generate.txt"), tagScriptedEffectBuffer.Bytes(), 0666)
+
+	opts.logf("生成国家成立脚本文件中...")
+	err = opts.writeTemplate(filepath.Join(modPath, "common", "ideas", "tew_attr_country_tag_auto_generate.txt"), "country_tag_ideas.txt.tmpl", data, false)
 	if err != nil {
 		return err
 	}
-	fmt.Println("生成国家成立脚本文件成功！")
-
-	fmt.Println("生成国家动态变化脚本文件中...")
-	var scriptedEffectBuffer bytes.Buffer
-	scriptedEffectBuffer.WriteString("# 更新国家类型\n#param: THIS\ntew_update_country_type = {\n\t# clear flag\n\tclr_country_flag = country_type_none\n\tclr_country_flag = country_type_anarchism\n\tclr_country_flag = country_type_communism\n\tclr_country_flag = country_type_democratic\n\tclr_country_flag = country_type_conservatism\n\tclr_country_flag = country_type_feudalism\n\tclr_country_flag = country_type_dictatorship\n\tclr_country_flag = country_type_fascism\n\n\t# set flag\n\tif = {\n\t\tlimit = { tew_can_ndependent_diplomacy = no }\n\t\tset_country_flag = country_type_none\n\t}\n\telse_if = {\n\t\tlimit = {\n\t\t\thas_idea = gov_anarchist_commune\n\t\t}\n\t\tset_country_flag = country_type_anarchism\n\t}\n\telse_if = {\n\t\tlimit = {\n\t\t\tOR = {\n\t\t\t\thas_idea = gov_communist_dictatorship\n\t\t\t\thas_idea = gov_communist_republic\n\t\t\t}\n\t\t}\n\t\tset_country_flag = country_type_communism\n\t}\n\telse_if = {\n\t\tlimit = {\n\t\t\tOR = {\n\t\t\t\thas_idea = gov_presidential_republic\n\t\t\t\thas_idea = gov_parliamentary_republic\n\t\t\t\thas_idea = gov_committee_republic\n\t\t\t}\n\t\t}\n\t\tset_country_flag = country_type_democratic\n\t}\n\telse_if = {\n\t\tlimit = {\n\t\t\thas_idea = gov_parliamentary_constitutional_monarchy\n\t\t}\n\t\tset_country_flag = country_type_conservatism\n\t}\n\telse_if = {\n\t\tlimit = {\n\t\t\tOR = {\n\t\t\t\thas_idea = gov_dualist_constitutional_monarchy\n\t\t\t\thas_idea = gov_absolute_monarchy\n\t\t\t}\n\t\t}\n\t\tset_country_flag = country_type_feudalism\n\t}\n\telse_if = {\n\t\tlimit = {\n\t\t\tOR = {\n\t\t\t\thas_idea = gov_presidential_dictatorship\n\t\t\t\thas_idea = gov_parliamentary_dictatorship\n\t\t\t\thas_idea = gov_military_dictatorship\n\t\t\t}\n\t\t}\n\t\tset_country_flag = country_type_dictatorship\n\t}\n\telse_if = {\n\t\tlimit = {\n\t\t\tOR = {\n\t\t\t\thas_idea = gov_fascist_republic\n\t\t\t\thas_idea = gov_fascist_dictatorship\n\t\t\t}\n\t\t}\n\t\tset_country_flag = country_type_fascism\n\t}\n\n\t# set cosmetic tag\n\tif = {\n\t\tlimit = { has_country_flag = country_type_none }\n\t\tdrop_cosmetic_tag = yes\n\t}\n\telse_if = {\n\t\tlimit = { NOT = { has_idea = country_tag_default } }\n")
-	var i int
-	for _, c := range canUpgradedCountries {
-		scriptedEffectBuffer.WriteString("\t\t")
-		scriptedEffectBuffer.WriteString(stlbasic.Ternary(i == 0, "if", "else_if"))
-		scriptedEffectBuffer.WriteString(" = {\n\t\t\tlimit = { has_idea = country_tag_")
-		scriptedEffectBuffer.WriteString(c.ID)
-		scriptedEffectBuffer.WriteString(" }\n")
+	opts.logf("生成国家成立脚本文件成功！")
 
-		var j int
-		for iter := countryTypes.Iterator(); iter.Next(); {
-			scriptedEffectBuffer.WriteString("\t\t\t")
-			scriptedEffectBuffer.WriteString(stlbasic.Ternary(j == 0, "if", "else_if"))
-			scriptedEffectBuffer.WriteString(" = {\n\t\t\t\tlimit = { has_country_flag = country_type_")
-			scriptedEffectBuffer.WriteString(iter.Value().First)
-			scriptedEffectBuffer.WriteString(" }\n\t\t\t\tset_cosmetic_tag = ")
-			scriptedEffectBuffer.WriteString(c.ID)
-			scriptedEffectBuffer.WriteString("_type_")
-			scriptedEffectBuffer.WriteString(iter.Value().First)
-			scriptedEffectBuffer.WriteString("\n\t\t\t}\n")
-			j++
-		}
-		scriptedEffectBuffer.WriteString("\t\t}\n")
-		i++
+	opts.logf("生成国家动态变化脚本文件中...")
+	err = opts.writeTemplate(filepath.Join(modPath, "common", "scripted_effects", "tew_tag_scripted_effects_auto_generate.txt"), "tag_scripted_effects.txt.tmpl", data, false)
+	if err != nil {
+		return err
 	}
-	scriptedEffectBuffer.WriteString("\t}\n\telse = {\n")
-	i = 0
-	for _, c := range config.Countries {
-		scriptedEffectBuffer.WriteString("\t\t")
-		scriptedEffectBuffer.WriteString(stlbasic.Ternary(i == 0, "if", "else_if"))
-		scriptedEffectBuffer.WriteString(" = {\n\t\t\tlimit = { original_tag = ")
-		scriptedEffectBuffer.WriteString(c.ID)
-		scriptedEffectBuffer.WriteString(" }\n")
+	opts.logf("生成国家动态变化脚本文件成功！")
 
-		var j int
-		for iter := countryTypes.Iterator(); iter.Next(); {
-			scriptedEffectBuffer.WriteString("\t\t\t")
-			scriptedEffectBuffer.WriteString(stlbasic.Ternary(j == 0, "if", "else_if"))
-			scriptedEffectBuffer.WriteString(" = {\n\t\t\t\tlimit = { has_country_flag = country_type_")
-			scriptedEffectBuffer.WriteString(iter.Value().First)
-			scriptedEffectBuffer.WriteString(" }\n\t\t\t\tset_cosmetic_tag = ")
-			scriptedEffectBuffer.WriteString(c.ID)
-			scriptedEffectBuffer.WriteString("_type_")
-			scriptedEffectBuffer.WriteString(iter.Value().First)
-			scriptedEffectBuffer.WriteString("\n\t\t\t}\n")
-			j++
-		}
-		scriptedEffectBuffer.WriteString("\t\t}\n")
-		i++
-	}
-	scriptedEffectBuffer.WriteString("\t}\n}\n")
-	err = os.WriteFile(filepath.Join(modPath, "common", "scripted_effects", "tew_tag_scripted_effects_auto_generate.txt"), scriptedEffectBuffer.Bytes(), 0666)
+	opts.logf("生成可变身国家名字文件中...")
+	err = opts.writeTemplate(filepath.Join(modPath, "localisation", "simp_chinese", "tew_country_tag_auto_generate_l_simp_chinese.yml"), "country_tag_l_simp_chinese.yml.tmpl", data, true)
 	if err != nil {
 		return err
 	}
-	fmt.Println("生成国家动态变化脚本文件成功！")
+	opts.logf("生成可变身国家名字文件成功！")
 
-	fmt.Println("生成可变身国家名字文件中...")
-	var canUpgradeCountryLocBuffer bytes.Buffer
-	canUpgradeCountryLocBuffer.WriteString("l_simp_chinese:\n country_tag:0 \"国家\"\n idea_group_country_tag:0 \"国家\"\n idea_group_country_tag_desc:0 \"国家\"\n country_tag_default:0 \"默认\"\n")
-	for _, c := range canUpgradedCountries {
-		canUpgradeCountryLocBuffer.WriteString(" country_tag_")
-		canUpgradeCountryLocBuffer.WriteString(c.ID)
-		canUpgradeCountryLocBuffer.WriteString(":0 \"")
-		canUpgradeCountryLocBuffer.WriteString(c.Name)
-		canUpgradeCountryLocBuffer.WriteString("\"\n")
-	}
-	err = util.WriteFileWithBOM(filepath.Join(modPath, "localisation", "simp_chinese", "tew_country_tag_auto_generate_l_simp_chinese.yml"), canUpgradeCountryLocBuffer.Bytes())
+	opts.logf("生成可变身国家图标文件中...")
+	err = opts.writeTemplate(filepath.Join(modPath, "interface", "tew_country_tga_auto_generate.gfx"), "country_tga.gfx.tmpl", data, false)
 	if err != nil {
 		return err
 	}
-	fmt.Println("生成可变身国家名字文件成功！")
+	opts.logf("生成可变身国家图标文件成功！")
+	return opts.commitFiles()
+}
 
-	fmt.Println("生成可变身国家图标文件中...")
-	var canUpgradeCountryIconBuffer bytes.Buffer
-	canUpgradeCountryIconBuffer.WriteString("spriteTypes = {")
-	for _, c := range canUpgradedCountries {
-		canUpgradeCountryIconBuffer.WriteString(fmt.Sprintf("\n\tspriteType = {\n\t\tname = \"GFX_idea_country_tag_%s\"\n\t\ttexturefile = \"gfx\\\\flags\\\\medium\\\\%s.tga\"\n\t}", c.ID, c.ID))
-		canUpgradeCountryIconBuffer.WriteString("\n")
+// saveCountries 将生成的国家颜色写回countries.json，使之后的生成结果保持不变
+func saveCountries(opts Options, generated int) error {
+	if config.CountriesPath == "" {
+		return fmt.Errorf("cannot find `config/countries.json` to save %d generated country colors, set `countries` in %s or $%s", generated, config.DefaultPathConfigFile, config.EnvCountriesPath)
 	}
-	canUpgradeCountryIconBuffer.WriteString("}")
-	err = os.WriteFile(filepath.Join(modPath, "interface", "tew_country_tga_auto_generate.gfx"), canUpgradeCountryIconBuffer.Bytes(), 0666)
+	data, err := config.EncodeCountries(config.CountryList)
 	if err != nil {
 		return err
 	}
-	fmt.Println("生成可变身国家图标文件成功！")
-	return nil
+	opts.logf("生成的%d个国家颜色将保存到%s", generated, config.CountriesPath)
+	return opts.writeFile(config.CountriesPath, data, false)
 }
@@ -0,0 +1,96 @@
+package sdk
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/kkkunny/TEW-hoi4/config"
+)
+
+var (
+	// countryTagRegexp hoi4的国家tag为3个字符，由大写字母和数字组成且以字母开头
+	countryTagRegexp = regexp.MustCompile(`^[A-Z][A-Z0-9]{2}$`)
+	// dynamicTagRegexp 游戏为动态国家（内战、解放等）保留的tag
+	dynamicTagRegexp = regexp.MustCompile(`^D[0-9]{2}$`)
+)
+
+// reservedTags 不能用作国家tag的词，包括脚本关键字和windows保留的文件名（旗帜等文件以tag命名）
+var reservedTags = []string{"AND", "NOT", "CON", "PRN", "AUX", "NUL"}
+
+// CheckCountries 检查countries.json中的国家
+// 包括重复的tag、不合法或保留的tag、不存在的地区、未知的子国家、子国家的循环以及超出0~100的upgrade_ratio
+func CheckCountries(opts Options) error {
+	errs, warns := validateCountries(opts, config.CountryList)
+	for _, err := range append(errs, warns...) {
+		opts.logf("%s", err.Error())
+	}
+	if n := len(errs) + len(warns); n != 0 {
+		return fmt.Errorf("found %d country problems", n)
+	}
+	opts.logf("国家检查通过！")
+	return nil
+}
+
+// validateCountries 检查国家列表，返回会导致生成错误的问题以及生成时可以忽略的问题
+// 未知的子国家会被跳过，因此只作为警告
+func validateCountries(opts Options, countries []*config.Country) (errs []error, warns []error) {
+	errorf := func(pos config.Position, format string, args ...any) {
+		errs = append(errs, fmt.Errorf("%s: %s", pos, fmt.Sprintf(format, args...)))
+	}
+	warnf := func(pos config.Position, format string, args ...any) {
+		warns = append(warns, fmt.Errorf("%s: %s", pos, fmt.Sprintf(format, args...)))
+	}
+
+	seen := make(map[string]*config.Country, len(countries))
+	for _, c := range countries {
+		if _, ok := seen[c.ID]; !ok {
+			seen[c.ID] = c
+		}
+	}
+	regions := make(map[string]bool)
+	for _, c := range countries {
+		if prev := seen[c.ID]; prev != c {
+			errorf(c.Pos("id"), "duplicate country `%s`, previous at %s", c.ID, prev.Pos("id"))
+		}
+
+		switch {
+		case !countryTagRegexp.MatchString(c.ID):
+			errorf(c.Pos("id"), "invalid country tag `%s`, expect 3 upper-case letters or digits starting with a letter", c.ID)
+		case dynamicTagRegexp.MatchString(c.ID) || slices.Contains(reservedTags, c.ID):
+			errorf(c.Pos("id"), "country tag `%s` is reserved", c.ID)
+		}
+
+		if c.Region == "" {
+			errorf(c.Pos("region"), "country `%s` missing region", c.ID)
+		} else {
+			exist, ok := regions[c.Region]
+			if !ok {
+				_, err := os.Stat(filepath.Join(opts.ModPath, "common", "countries", c.Region+".txt"))
+				exist = err == nil
+				regions[c.Region] = exist
+			}
+			if !exist {
+				errorf(c.Pos("region"), "country `%s`: unknown region `%s`, missing common/countries/%s.txt", c.ID, c.Region, c.Region)
+			}
+		}
+
+		if ratio, ok := c.UpgradeRatio.Value(); ok && (ratio < 0 || ratio > 100) {
+			errorf(c.Pos("upgrade_ratio"), "country `%s`: upgrade_ratio %d out of range 0~100", c.ID, ratio)
+		}
+
+		for _, son := range c.Sons.ValueWith(nil) {
+			if _, ok := seen[son]; !ok {
+				warnf(c.Pos("sons"), "country `%s`: unknown son `%s`", c.ID, son)
+			}
+		}
+	}
+
+	for _, cycle := range NewCountryHierarchy(countries).Cycles() {
+		errorf(seen[cycle[0]].Pos("sons"), "cycle in sons: %s", strings.Join(cycle, " -> "))
+	}
+	return errs, warns
+}
@@ -0,0 +1,61 @@
+package sdk
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/kkkunny/stl/container/optional"
+
+	"github.com/kkkunny/TEW-hoi4/config"
+	"github.com/kkkunny/TEW-hoi4/internal/testutil"
+)
+
+func TestValidateCountries(t *testing.T) {
+	european := func(id string, sons ...string) *config.Country {
+		c := testCountry(id, sons...)
+		c.Region = "European"
+		return c
+	}
+	withRatio := func(c *config.Country, ratio int64) *config.Country {
+		c.UpgradeRatio = optional.Some(ratio)
+		return c
+	}
+	tests := []struct {
+		name      string
+		countries []*config.Country
+		errs      []string
+		warns     []string
+	}{
+		{name: "valid", countries: []*config.Country{european("FRA", "GER"), withRatio(european("GER"), 100), european("A00")}},
+		{name: "invalid tag", countries: []*config.Country{european("fra"), european("1AB"), european("FRAN")}, errs: []string{"invalid country tag `fra`", "invalid country tag `1AB`", "invalid country tag `FRAN`"}},
+		{name: "reserved tag", countries: []*config.Country{european("CON"), european("AND")}, errs: []string{"country tag `CON` is reserved", "country tag `AND` is reserved"}},
+		{name: "dynamic tag", countries: []*config.Country{european("D01"), european("D99")}, errs: []string{"country tag `D01` is reserved", "country tag `D99` is reserved"}},
+		{name: "missing region", countries: []*config.Country{testCountry("FRA")}, errs: []string{"country `FRA` missing region"}},
+		{name: "unknown region", countries: []*config.Country{{ID: "FRA", Region: "Martian"}}, errs: []string{"unknown region `Martian`"}},
+		{name: "ratio out of range", countries: []*config.Country{withRatio(european("FRA"), -1), withRatio(european("GER"), 101)}, errs: []string{"upgrade_ratio -1 out of range", "upgrade_ratio 101 out of range"}},
+		{name: "parent cycle", countries: []*config.Country{european("FRA", "GER"), european("GER", "ITA"), european("ITA", "FRA")}, errs: []string{"cycle in sons: FRA -> GER -> ITA -> FRA"}},
+		{name: "duplicate", countries: []*config.Country{european("FRA"), european("GER"), european("FRA")}, errs: []string{"duplicate country `FRA`"}},
+		{name: "unknown son", countries: []*config.Country{european("FRA", "XXX")}, warns: []string{"unknown son `XXX`"}},
+	}
+	opts := Options{ModPath: testutil.ModPath()}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errs, warns := validateCountries(opts, tt.countries)
+			checkErrors(t, "errors", errs, tt.errs)
+			checkErrors(t, "warnings", warns, tt.warns)
+		})
+	}
+}
+
+// checkErrors 检查errs与want一一对应，每个错误包含对应的字符串
+func checkErrors(t *testing.T, kind string, errs []error, want []string) {
+	t.Helper()
+	if len(errs) != len(want) {
+		t.Fatalf("expect %d %s, got %v", len(want), kind, errs)
+	}
+	for i, err := range errs {
+		if !strings.Contains(err.Error(), want[i]) {
+			t.Errorf("expect %s %d to contain %q, got %q", kind, i, want[i], err.Error())
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package sdk
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/kkkunny/TEW-hoi4/config"
+	"github.com/kkkunny/TEW-hoi4/util"
+)
+
+// FlagSize 旗帜尺寸
+type FlagSize struct {
+	Dir    string
+	Width  uint16
+	Height uint16
+}
+
+var (
+	// SmallFlag 小旗帜
+	SmallFlag = FlagSize{Dir: "small", Width: 10, Height: 7}
+	// MediumFlag 中旗帜
+	MediumFlag = FlagSize{Dir: "medium", Width: 41, Height: 26}
+)
+
+// ResizeFlags 按gfx/flags下的旗帜重新生成指定尺寸的旗帜
+func ResizeFlags(opts Options, sizes ...FlagSize) error {
+	flagPath := filepath.Join(opts.ModPath, "gfx", "flags")
+	flagInfos, err := os.ReadDir(flagPath)
+	if err != nil {
+		return err
+	}
+	for _, size := range sizes {
+		opts.logf("生成%s旗帜中...", size.Dir)
+		opts.beginFiles("flags/" + size.Dir)
+		sizePath := filepath.Join(flagPath, size.Dir)
+		var count int
+		for _, flagInfo := range flagInfos {
+			if flagInfo.IsDir() || !strings.HasSuffix(flagInfo.Name(), ".tga") {
+				continue
+			}
+			count++
+			data, err := util.ResizeTgaImage(filepath.Join(flagPath, flagInfo.Name()), size.Width, size.Height)
+			if err != nil {
+				return fmt.Errorf("`%s` resize error: %s", flagInfo.Name(), err.Error())
+			}
+			err = opts.writeFile(filepath.Join(sizePath, flagInfo.Name()), data, false)
+			if err != nil {
+				return err
+			}
+		}
+		err = opts.commitFiles()
+		if err != nil {
+			return err
+		}
+		opts.logf("生成%s旗帜成功，共%d个！", size.Dir, count)
+	}
+	return nil
+}
+
+// CheckFlags 检查每个国家及其各类型外观tag的旗帜是否齐全
+// 缺少国家旗帜视为错误；缺少外观tag旗帜时游戏会使用国家旗帜，只给出提示
+func CheckFlags(opts Options) error {
+	flagPath := filepath.Join(opts.ModPath, "gfx", "flags")
+	exist := func(dir, tag string) bool {
+		_, err := os.Stat(filepath.Join(flagPath, dir, tag+".tga"))
+		return err == nil
+	}
+
+	var missing int
+	for _, c := range config.CountryList {
+		for _, dir := range []string{"", SmallFlag.Dir, MediumFlag.Dir} {
+			if !exist(dir, c.ID) {
+				missing++
+				opts.logf("缺少国家%s的旗帜 %s", c.ID, opts.rel(filepath.Join(flagPath, dir, c.ID+".tga")))
+			}
+		}
+		var missingTypes []string
+		for _, ct := range config.CountryTypes {
+			if flag := c.TypeOverride(ct.ID).Flag; flag != "" {
+				if _, err := os.Stat(filepath.Join(flagPath, filepath.FromSlash(flag))); err != nil {
+					missing++
+					opts.logf("国家%s的%s类型指定的旗帜 %s 不存在", c.ID, ct.ID, flag)
+				}
+			}
+			if !exist("", ct.CosmeticTag(c.ID)) {
+				missingTypes = append(missingTypes, ct.ID)
+			}
+		}
+		if len(missingTypes) != 0 {
+			opts.debugf("国家%s的以下类型没有单独的旗帜，将使用国家旗帜：%s", c.ID, strings.Join(missingTypes, " "))
+		}
+	}
+	if missing != 0 {
+		return fmt.Errorf("missing %d flags", missing)
+	}
+	opts.logf("旗帜检查通过！")
+	return nil
+}
+
+// copyFlag 将gfx/flags下的旗帜from复制为tag的旗帜，并生成对应的小旗帜和中旗帜
+func copyFlag(opts Options, from, tag string) error {
+	flagPath := filepath.Join(opts.ModPath, "gfx", "flags")
+	src := filepath.Join(flagPath, filepath.FromSlash(from))
+	data, err := os.ReadFile(src)
+	if err != nil {
+		return err
+	}
+	err = opts.writeFile(filepath.Join(flagPath, tag+".tga"), data, false)
+	if err != nil {
+		return err
+	}
+	for _, size := range []FlagSize{SmallFlag, MediumFlag} {
+		data, err = util.ResizeTgaImage(src, size.Width, size.Height)
+		if err != nil {
+			return err
+		}
+		err = opts.writeFile(filepath.Join(flagPath, size.Dir, tag+".tga"), data, false)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
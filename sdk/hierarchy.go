@@ -0,0 +1,217 @@
+package sdk
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/kkkunny/TEW-hoi4/config"
+)
+
+// defaultUpgradeRatio 未设置upgrade_ratio时成立可变身国家需要控制的子国家比例
+const defaultUpgradeRatio = 70
+
+// CountryHierarchy 由countries.json中的sons构成的可变身国家层级
+// 一个国家可以属于多个可变身国家，因此层级是有向无环图而不是树
+type CountryHierarchy struct {
+	countries map[string]*config.Country
+	// order 国家在countries.json中的顺序
+	order []string
+	// sons 直接的子国家，不含未知的tag
+	sons map[string][]string
+	// members 所有直接或间接的子国家
+	members map[string][]string
+	// formers 直接或间接包含该国家的可变身国家
+	formers map[string][]string
+	cycles  [][]string
+}
+
+// NewCountryHierarchy 由国家列表构建层级，存在环时仍会构建完成，环中形成回路的子国家关系被忽略
+func NewCountryHierarchy(countries []*config.Country) *CountryHierarchy {
+	h := &CountryHierarchy{
+		countries: make(map[string]*config.Country, len(countries)),
+		sons:      make(map[string][]string),
+		members:   make(map[string][]string),
+		formers:   make(map[string][]string),
+	}
+	for _, c := range countries {
+		// 重复的国家只使用第一个定义，与validateCountries一致
+		if _, ok := h.countries[c.ID]; !ok {
+			h.order = append(h.order, c.ID)
+			h.countries[c.ID] = c
+		}
+	}
+	for _, id := range h.order {
+		for _, son := range h.countries[id].Sons.ValueWith(nil) {
+			if _, ok := h.countries[son]; ok && !slices.Contains(h.sons[id], son) {
+				h.sons[id] = append(h.sons[id], son)
+			}
+		}
+	}
+
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	states := make(map[string]int, len(h.order))
+	var stack []string
+	var visit func(id string)
+	visit = func(id string) {
+		states[id] = visiting
+		stack = append(stack, id)
+		var members []string
+		for _, son := range h.sons[id] {
+			switch states[son] {
+			case unvisited:
+				visit(son)
+			case visiting:
+				start := slices.Index(stack, son)
+				h.cycles = append(h.cycles, append(slices.Clone(stack[start:]), son))
+				continue
+			}
+			for _, m := range append(slices.Clone(h.members[son]), son) {
+				if m != id && !slices.Contains(members, m) {
+					members = append(members, m)
+				}
+			}
+		}
+		h.members[id] = members
+		stack = stack[:len(stack)-1]
+		states[id] = visited
+	}
+	for _, id := range h.order {
+		if states[id] == unvisited {
+			visit(id)
+		}
+	}
+
+	for _, id := range h.Formables() {
+		for _, m := range h.members[id] {
+			h.formers[m] = append(h.formers[m], id)
+		}
+	}
+	return h
+}
+
+// Country 返回tag对应的国家
+func (h *CountryHierarchy) Country(tag string) (*config.Country, bool) {
+	c, ok := h.countries[tag]
+	return c, ok
+}
+
+// Cycles 返回子国家关系中的环，每个环以起点结尾
+func (h *CountryHierarchy) Cycles() [][]string {
+	return h.cycles
+}
+
+// Formables 按countries.json中的顺序返回有子国家的可变身国家
+func (h *CountryHierarchy) Formables() []string {
+	var formables []string
+	for _, id := range h.order {
+		if len(h.sons[id]) != 0 {
+			formables = append(formables, id)
+		}
+	}
+	return formables
+}
+
+// Roots 返回不属于其他可变身国家的可变身国家
+func (h *CountryHierarchy) Roots() []string {
+	var roots []string
+	for _, id := range h.Formables() {
+		if len(h.formers[id]) == 0 {
+			roots = append(roots, id)
+		}
+	}
+	return roots
+}
+
+// Sons 返回直接的子国家
+func (h *CountryHierarchy) Sons(tag string) []string {
+	return h.sons[tag]
+}
+
+// Members 返回所有直接或间接的子国家，子国家的成员排在子国家之前
+func (h *CountryHierarchy) Members(tag string) []string {
+	return h.members[tag]
+}
+
+// Formers 按countries.json中的顺序返回直接或间接包含该国家的可变身国家
+func (h *CountryHierarchy) Formers(tag string) []string {
+	return h.formers[tag]
+}
+
+// Conflicts 返回与该可变身国家争夺同一子国家的其他可变身国家，不含它自己的子国家
+func (h *CountryHierarchy) Conflicts(tag string) []string {
+	members := h.members[tag]
+	var conflicts []string
+	for _, m := range members {
+		for _, former := range h.formers[m] {
+			if former == tag || slices.Contains(members, former) || slices.Contains(conflicts, former) {
+				continue
+			}
+			conflicts = append(conflicts, former)
+		}
+	}
+	return conflicts
+}
+
+// Print 以树的形式输出tags的层级，tags为空时输出所有顶层的可变身国家
+func (h *CountryHierarchy) Print(w io.Writer, tags ...string) error {
+	if len(tags) == 0 {
+		tags = h.Roots()
+	}
+	var buf strings.Builder
+	var printNode func(tag, prefix string, last bool, depth int)
+	printNode = func(tag, prefix string, last bool, depth int) {
+		var childPrefix string
+		if depth != 0 {
+			buf.WriteString(prefix)
+			if last {
+				buf.WriteString("└── ")
+				childPrefix = prefix + "    "
+			} else {
+				buf.WriteString("├── ")
+				childPrefix = prefix + "│   "
+			}
+		}
+		buf.WriteString(h.describe(tag, depth == 0))
+		buf.WriteString("\n")
+		for i, son := range h.sons[tag] {
+			printNode(son, childPrefix, i == len(h.sons[tag])-1, depth+1)
+		}
+	}
+	for _, tag := range tags {
+		if _, ok := h.countries[tag]; !ok {
+			return fmt.Errorf("unknown country `%s`", tag)
+		}
+		printNode(tag, "", true, 0)
+	}
+	_, err := io.WriteString(w, buf.String())
+	return err
+}
+
+// describe 返回国家在树中的描述，顶层的可变身国家附带成立比例和冲突的可变身国家
+func (h *CountryHierarchy) describe(tag string, top bool) string {
+	c := h.countries[tag]
+	desc := c.ID + " " + c.Name
+	if !top || len(h.sons[tag]) == 0 {
+		return desc
+	}
+	desc += fmt.Sprintf("（成立比例%d%%", c.UpgradeRatio.ValueWith(defaultUpgradeRatio))
+	if conflicts := h.Conflicts(tag); len(conflicts) != 0 {
+		desc += "，与" + strings.Join(conflicts, "、") + "冲突"
+	}
+	return desc + "）"
+}
+
+// PrintCountryTree 输出可变身国家的层级，tags为空时输出所有顶层的可变身国家
+func PrintCountryTree(opts Options, tags ...string) error {
+	h := NewCountryHierarchy(config.CountryList)
+	if cycles := h.Cycles(); len(cycles) != 0 {
+		return fmt.Errorf("cycle in sons: %s", strings.Join(cycles[0], " -> "))
+	}
+	return h.Print(opts.out(), tags...)
+}
@@ -0,0 +1,112 @@
+package sdk
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/kkkunny/stl/container/optional"
+
+	"github.com/kkkunny/TEW-hoi4/config"
+)
+
+func testCountry(id string, sons ...string) *config.Country {
+	c := &config.Country{ID: id}
+	if len(sons) != 0 {
+		c.Sons = optional.Some(sons)
+	}
+	return c
+}
+
+func TestCountryHierarchy(t *testing.T) {
+	tests := []struct {
+		name      string
+		countries []*config.Country
+		cycles    [][]string
+		roots     []string
+		members   map[string][]string
+		formers   map[string][]string
+		conflicts map[string][]string
+	}{
+		{
+			name:      "self loop",
+			countries: []*config.Country{testCountry("AAA", "AAA")},
+			cycles:    [][]string{{"AAA", "AAA"}},
+			roots:     []string{"AAA"},
+			members:   map[string][]string{"AAA": nil},
+			formers:   map[string][]string{"AAA": nil},
+		},
+		{
+			name:      "three cycle",
+			countries: []*config.Country{testCountry("AAA", "BBB"), testCountry("BBB", "CCC"), testCountry("CCC", "AAA")},
+			cycles:    [][]string{{"AAA", "BBB", "CCC", "AAA"}},
+			roots:     []string{"AAA"},
+			members:   map[string][]string{"AAA": {"CCC", "BBB"}, "BBB": {"CCC"}, "CCC": nil},
+			formers:   map[string][]string{"AAA": nil, "BBB": {"AAA"}, "CCC": {"AAA", "BBB"}},
+		},
+		{
+			name: "diamond",
+			countries: []*config.Country{
+				testCountry("AAA", "BBB", "CCC"),
+				testCountry("BBB", "DDD"),
+				testCountry("CCC", "DDD"),
+				testCountry("DDD"),
+			},
+			roots:     []string{"AAA"},
+			members:   map[string][]string{"AAA": {"DDD", "BBB", "CCC"}, "BBB": {"DDD"}, "DDD": nil},
+			formers:   map[string][]string{"DDD": {"AAA", "BBB", "CCC"}, "BBB": {"AAA"}},
+			conflicts: map[string][]string{"AAA": nil, "BBB": {"AAA", "CCC"}},
+		},
+		{
+			name: "former conflict",
+			countries: []*config.Country{
+				testCountry("XXX", "PPP", "QQQ"),
+				testCountry("YYY", "QQQ", "RRR"),
+				testCountry("PPP"), testCountry("QQQ"), testCountry("RRR"),
+			},
+			roots:     []string{"XXX", "YYY"},
+			members:   map[string][]string{"XXX": {"PPP", "QQQ"}, "YYY": {"QQQ", "RRR"}},
+			formers:   map[string][]string{"PPP": {"XXX"}, "QQQ": {"XXX", "YYY"}, "RRR": {"YYY"}},
+			conflicts: map[string][]string{"XXX": {"YYY"}, "YYY": {"XXX"}},
+		},
+		{
+			name:      "duplicate keeps first",
+			countries: []*config.Country{testCountry("AAA", "BBB"), testCountry("BBB"), testCountry("CCC"), testCountry("AAA", "CCC")},
+			roots:     []string{"AAA"},
+			members:   map[string][]string{"AAA": {"BBB"}},
+			formers:   map[string][]string{"BBB": {"AAA"}, "CCC": nil},
+		},
+		{
+			name:      "unknown son",
+			countries: []*config.Country{testCountry("AAA", "ZZZ", "BBB"), testCountry("BBB")},
+			roots:     []string{"AAA"},
+			members:   map[string][]string{"AAA": {"BBB"}},
+			formers:   map[string][]string{"BBB": {"AAA"}, "ZZZ": nil},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewCountryHierarchy(tt.countries)
+			if got := h.Cycles(); !reflect.DeepEqual(got, tt.cycles) {
+				t.Errorf("Cycles() = %v, want %v", got, tt.cycles)
+			}
+			if got := h.Roots(); !reflect.DeepEqual(got, tt.roots) {
+				t.Errorf("Roots() = %v, want %v", got, tt.roots)
+			}
+			for tag, want := range tt.members {
+				if got := h.Members(tag); !reflect.DeepEqual(got, want) {
+					t.Errorf("Members(%s) = %v, want %v", tag, got, want)
+				}
+			}
+			for tag, want := range tt.formers {
+				if got := h.Formers(tag); !reflect.DeepEqual(got, want) {
+					t.Errorf("Formers(%s) = %v, want %v", tag, got, want)
+				}
+			}
+			for tag, want := range tt.conflicts {
+				if got := h.Conflicts(tag); !reflect.DeepEqual(got, want) {
+					t.Errorf("Conflicts(%s) = %v, want %v", tag, got, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,126 @@
+package sdk
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"golang.org/x/exp/maps"
+
+	"github.com/kkkunny/TEW-hoi4/config"
+)
+
+var locKeyRegexp = regexp.MustCompile(`^\s*([^\s:#"]+)\s*:\s*\d*\s*"`)
+
+// CheckLocalisation 检查mod中的本地化文件
+// 包括文件是否带有BOM、文件头是否与语言目录一致、同一语言中是否有重复的键以及国家名字是否齐全
+func CheckLocalisation(opts Options) error {
+	dirPath := filepath.Join(opts.ModPath, "localisation")
+	langInfos, err := os.ReadDir(dirPath)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	chineseKeys := make(map[string]config.Position)
+	for _, langInfo := range langInfos {
+		if !langInfo.IsDir() {
+			continue
+		}
+		lang := langInfo.Name()
+		langPath := filepath.Join(dirPath, lang)
+		locInfos, err := os.ReadDir(langPath)
+		if err != nil {
+			return err
+		}
+		keys := make(map[string]config.Position)
+		for _, locInfo := range locInfos {
+			if locInfo.IsDir() || !strings.HasSuffix(locInfo.Name(), ".yml") {
+				continue
+			}
+			fp := filepath.Join(langPath, locInfo.Name())
+			opts.debugf("检查 %s", opts.rel(fp))
+			fileErrs, err := checkLocalisationFile(opts.rel(fp), fp, lang, keys)
+			if err != nil {
+				return err
+			}
+			errs = append(errs, fileErrs...)
+		}
+		if lang == "simp_chinese" {
+			chineseKeys = keys
+		}
+	}
+
+	ids := maps.Keys(config.Countries)
+	slices.Sort(ids)
+	for _, id := range ids {
+		for _, key := range []string{id, id + "_DEF", id + "_ADJ"} {
+			if _, ok := chineseKeys[key]; !ok {
+				errs = append(errs, fmt.Errorf("country `%s` missing localisation key `%s` in simp_chinese", id, key))
+			}
+		}
+	}
+
+	for _, err := range errs {
+		opts.logf("%s", err.Error())
+	}
+	if len(errs) != 0 {
+		return fmt.Errorf("found %d localisation problems", len(errs))
+	}
+	opts.logf("本地化检查通过！")
+	return nil
+}
+
+// checkLocalisationFile 检查单个本地化文件，keys记录同一语言中已出现的键
+func checkLocalisationFile(name, path, lang string, keys map[string]config.Position) ([]error, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var errs []error
+	if !bytes.HasPrefix(data, []byte("\xEF\xBB\xBF")) {
+		errs = append(errs, fmt.Errorf("%s: missing utf-8 BOM", name))
+	}
+	data = bytes.TrimPrefix(data, []byte("\xEF\xBB\xBF"))
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(nil, 1024*1024)
+	var header bool
+	for line := 1; scanner.Scan(); line++ {
+		text := scanner.Text()
+		trimmed := strings.TrimSpace(text)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		if !header {
+			header = true
+			if trimmed != "l_"+lang+":" {
+				errs = append(errs, fmt.Errorf("%s: expect header `l_%s:`, got `%s`", config.Position{Path: name, Line: line}, lang, trimmed))
+			}
+			continue
+		}
+		match := locKeyRegexp.FindStringSubmatch(text)
+		if match == nil {
+			continue
+		}
+		pos := config.Position{Path: name, Line: line}
+		if prev, ok := keys[match[1]]; ok {
+			errs = append(errs, fmt.Errorf("%s: duplicate key `%s`, previous at %s", pos, match[1], prev))
+			continue
+		}
+		keys[match[1]] = pos
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, err
+	}
+	if !header {
+		errs = append(errs, fmt.Errorf("%s: missing header `l_%s:`", name, lang))
+	}
+	return errs, nil
+}
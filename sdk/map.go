@@ -0,0 +1,59 @@
+package sdk
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/kkkunny/TEW-hoi4/parser/history"
+	_map "github.com/kkkunny/TEW-hoi4/parser/map"
+)
+
+// CheckMap 检查mod中的地图文件
+// 包括definition.csv中重复的省份id与颜色、adjacencies.csv引用的省份，以及省份与地区、战略区域、补给区域之间的从属关系
+func CheckMap(opts Options) error {
+	mapPath := filepath.Join(opts.ModPath, "map")
+	definition, err := _map.ParseDefinition(filepath.Join(mapPath, "definition.csv"))
+	if err != nil {
+		return err
+	}
+	errs := definition.Validate()
+	defs := definition.Map()
+
+	adjPath := filepath.Join(mapPath, "adjacencies.csv")
+	if _, err = os.Stat(adjPath); err == nil {
+		adjs, err := _map.ParseAdjacencies(adjPath)
+		if err != nil {
+			return err
+		}
+		adjErrs, warns := adjs.Validate(defs)
+		for _, err := range warns {
+			opts.logf("warning: %s", err.Error())
+		}
+		errs = append(errs, adjErrs...)
+	}
+
+	states, err := history.ParseStateDir(opts.ModPath)
+	if err != nil {
+		return err
+	}
+	regions, err := _map.ParseStrategicRegionDir(opts.ModPath)
+	if err != nil {
+		return err
+	}
+	areas, err := _map.ParseSupplyAreaDir(opts.ModPath)
+	if err != nil {
+		return err
+	}
+	opts.debugf("共%d个省份、%d个地区、%d个战略区域、%d个补给区域", len(defs), len(states), len(regions), len(areas))
+	errs = append(errs, _map.LinkMap(defs, states, regions, areas).Validate()...)
+
+	for _, err := range errs {
+		opts.logf("%s", err.Error())
+	}
+	if len(errs) != 0 {
+		return fmt.Errorf("found %d map problems", len(errs))
+	}
+	opts.logf("地图检查通过！")
+	return nil
+}
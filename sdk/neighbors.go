@@ -0,0 +1,81 @@
+package sdk
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"golang.org/x/exp/maps"
+
+	"github.com/kkkunny/TEW-hoi4/parser/history"
+	_map "github.com/kkkunny/TEW-hoi4/parser/map"
+)
+
+// countryNeighbors 根据地区的拥有者计算相邻的国家
+// 省份的相邻关系来自provinces.bmp中相接的陆地省份，并按adjacencies.csv加入海峡、去掉不可通行的相邻，mod中没有地图文件时返回nil
+func countryNeighbors(opts Options) (map[string][]string, error) {
+	defPath := filepath.Join(opts.ModPath, "map", "definition.csv")
+	bmpPath := filepath.Join(opts.ModPath, "map", "provinces.bmp")
+	for _, p := range []string{defPath, bmpPath} {
+		if _, err := os.Stat(p); errors.Is(err, os.ErrNotExist) {
+			opts.debugf("未找到%s，不计算相邻国家", opts.rel(p))
+			return nil, nil
+		}
+	}
+
+	states, err := history.ParseStateDir(opts.ModPath)
+	if err != nil {
+		return nil, err
+	}
+	definition, err := _map.ParseDefinition(defPath)
+	if err != nil {
+		return nil, err
+	}
+	defs := definition.Map()
+	provinceMap, err := _map.ParseProvinceMap(bmpPath, defs)
+	if err != nil {
+		return nil, err
+	}
+	var adjs []*_map.Adjacency
+	adjPath := filepath.Join(opts.ModPath, "map", "adjacencies.csv")
+	if _, err = os.Stat(adjPath); err == nil {
+		adjacencies, err := _map.ParseAdjacencies(adjPath)
+		if err != nil {
+			return nil, err
+		}
+		adjs = adjacencies.List()
+	}
+	provinceNeighbors := provinceMap.Neighbors(adjs...)
+
+	owners := make(map[int64]string)
+	for _, state := range states {
+		if state.History.Owner == "" {
+			continue
+		}
+		for _, p := range state.Provinces {
+			if def, ok := defs[p]; ok && def.Type == _map.ProvinceTypeLand {
+				owners[p] = state.History.Owner
+			}
+		}
+	}
+	neighborSets := make(map[string]map[string]struct{})
+	for p, owner := range owners {
+		for _, np := range provinceNeighbors[p] {
+			nOwner, ok := owners[np]
+			if !ok || nOwner == owner {
+				continue
+			}
+			if _, ok = neighborSets[owner]; !ok {
+				neighborSets[owner] = make(map[string]struct{})
+			}
+			neighborSets[owner][nOwner] = struct{}{}
+		}
+	}
+	neighbors := make(map[string][]string, len(neighborSets))
+	for owner, set := range neighborSets {
+		neighbors[owner] = maps.Keys(set)
+		slices.Sort(neighbors[owner])
+	}
+	return neighbors, nil
+}
@@ -0,0 +1,79 @@
+package sdk
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/kkkunny/TEW-hoi4/util"
+)
+
+// Options 各任务的公共选项
+type Options struct {
+	// ModPath mod根目录
+	ModPath string
+	// GamePath 游戏根目录
+	GamePath string
+	// DryRun 只输出将要写入的文件及其变化，不修改磁盘
+	DryRun bool
+	// Diff DryRun时输出与磁盘上文件的unified diff，而不是变化的摘要
+	Diff bool
+	// Seed 随机数种子，与国家tag一起决定生成的颜色等，相同的种子得到相同的结果
+	Seed uint64
+	// Verbose 输出详细信息
+	Verbose bool
+	// Out 输出信息，为nil时使用标准输出
+	Out io.Writer
+
+	// files 生成的文件，由commitFiles统一写入
+	files *FileSet
+	// generator 生成这些文件的生成器名称，为空时不记录到清单中
+	generator string
+}
+
+func (opts *Options) out() io.Writer {
+	if opts.Out == nil {
+		return os.Stdout
+	}
+	return opts.Out
+}
+
+// logf 输出进度信息
+func (opts *Options) logf(format string, args ...any) {
+	fmt.Fprintf(opts.out(), format+"\n", args...)
+}
+
+// debugf 输出详细信息，只在Verbose时输出
+func (opts *Options) debugf(format string, args ...any) {
+	if opts.Verbose {
+		fmt.Fprintf(opts.out(), format+"\n", args...)
+	}
+}
+
+// rel 返回相对于mod根目录的路径
+func (opts *Options) rel(path string) string {
+	rel, err := filepath.Rel(opts.ModPath, path)
+	if err != nil {
+		return path
+	}
+	return filepath.ToSlash(rel)
+}
+
+// writeFile 将mod中的文件写入文件集合，withBOM为真时以带BOM的utf-8写入
+// 需要先调用beginFiles，文件在commitFiles时才写入磁盘
+func (opts *Options) writeFile(path string, data []byte, withBOM bool) error {
+	if opts.files == nil {
+		return fmt.Errorf("write `%s` outside of file set", opts.rel(path))
+	}
+	if withBOM {
+		data = util.WithBOM(data)
+	}
+	opts.files.Write(path, data)
+	return nil
+}
+
+// beginFiles 开始收集生成器generator生成的文件，generator为空时不记录到清单中，也不清理旧文件
+func (opts *Options) beginFiles(generator string) {
+	opts.files, opts.generator = NewFileSet(), generator
+}
@@ -0,0 +1,284 @@
+package sdk
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"unicode/utf8"
+
+	"github.com/kkkunny/TEW-hoi4/config"
+	"github.com/kkkunny/TEW-hoi4/util"
+)
+
+// FileStatus 生成的文件相对于磁盘上文件的状态
+type FileStatus int
+
+const (
+	// FileUnchanged 内容与磁盘上的文件相同
+	FileUnchanged FileStatus = iota
+	// FileAdded 磁盘上不存在该文件
+	FileAdded
+	// FileModified 内容与磁盘上的文件不同
+	FileModified
+	// FileRemoved 上次生成而本次不再生成的文件，将被删除
+	FileRemoved
+)
+
+func (s FileStatus) String() string {
+	switch s {
+	case FileAdded:
+		return "新增"
+	case FileModified:
+		return "修改"
+	case FileRemoved:
+		return "删除"
+	default:
+		return "未变"
+	}
+}
+
+// FileSet 虚拟的文件集合，生成器先写入其中，最后统一写入磁盘或与磁盘上的文件比较
+type FileSet struct {
+	paths []string
+	files map[string][]byte
+}
+
+// NewFileSet 创建空的文件集合
+func NewFileSet() *FileSet {
+	return &FileSet{files: make(map[string][]byte)}
+}
+
+// Write 写入文件，同一路径多次写入时保留最后一次的内容
+func (fs *FileSet) Write(path string, data []byte) {
+	path = filepath.Clean(path)
+	if _, ok := fs.files[path]; !ok {
+		fs.paths = append(fs.paths, path)
+	}
+	fs.files[path] = data
+}
+
+// Paths 按写入顺序返回所有文件路径
+func (fs *FileSet) Paths() []string {
+	return fs.paths
+}
+
+// Get 获取文件内容
+func (fs *FileSet) Get(path string) ([]byte, bool) {
+	data, ok := fs.files[filepath.Clean(path)]
+	return data, ok
+}
+
+// Status 比较文件与磁盘上的文件，返回状态与磁盘上的内容
+func (fs *FileSet) Status(path string) (FileStatus, []byte, error) {
+	data, _ := fs.Get(path)
+	old, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return FileAdded, nil, nil
+	} else if err != nil {
+		return 0, nil, err
+	} else if bytes.Equal(old, data) {
+		return FileUnchanged, old, nil
+	}
+	return FileModified, old, nil
+}
+
+// Manifest 生成器名称到其生成的文件（相对于mod根目录）的映射
+type Manifest map[string][]string
+
+// manifestPath 记录该mod中各生成器所生成文件的清单，用于清理不再生成的文件
+// 清单保存在config.StatePath下而不是mod中，以免随mod发布；不同的mod按根目录的绝对路径区分
+func (opts *Options) manifestPath() (string, error) {
+	if config.StatePath == "" {
+		return "", errors.New("state path is not set, set it with $" + config.EnvStatePath)
+	}
+	modPath, err := filepath.Abs(opts.ModPath)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256([]byte(modPath))
+	return filepath.Join(config.StatePath, "manifests", fmt.Sprintf("%s-%x.json", filepath.Base(modPath), sum[:8])), nil
+}
+
+func (opts *Options) loadManifest() (Manifest, error) {
+	path, err := opts.manifestPath()
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return make(Manifest), nil
+	} else if err != nil {
+		return nil, err
+	}
+	var manifest Manifest
+	err = json.Unmarshal(data, &manifest)
+	if err != nil {
+		return nil, fmt.Errorf("`%s` parse error: %s", path, err.Error())
+	}
+	if manifest == nil {
+		manifest = make(Manifest)
+	}
+	return manifest, nil
+}
+
+func (opts *Options) saveManifest(manifest Manifest) error {
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	path, err := opts.manifestPath()
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(filepath.Dir(path), 0777)
+	if err != nil {
+		return err
+	}
+	_, err = util.WriteFileAtomic(path, append(data, '\n'), 0666)
+	return err
+}
+
+// staleFiles 返回清单中该生成器上次生成而本次没有生成的文件，以及本次生成的mod内文件
+func (opts *Options) staleFiles(manifest Manifest, generator string, files *FileSet) (stale []string, generated []string) {
+	current := make(map[string]struct{}, len(files.Paths()))
+	for _, path := range files.Paths() {
+		rel, err := filepath.Rel(opts.ModPath, path)
+		if err != nil || !filepath.IsLocal(rel) {
+			// mod外的文件（如countries.json）不记录在清单中，也不会被删除
+			continue
+		}
+		rel = filepath.ToSlash(rel)
+		current[rel] = struct{}{}
+		generated = append(generated, rel)
+	}
+	for _, rel := range manifest[generator] {
+		if _, ok := current[rel]; ok || !filepath.IsLocal(filepath.FromSlash(rel)) {
+			continue
+		}
+		path := filepath.Join(opts.ModPath, filepath.FromSlash(rel))
+		if _, err := os.Stat(path); err == nil {
+			stale = append(stale, path)
+		}
+	}
+	slices.Sort(generated)
+	return stale, generated
+}
+
+// commitFiles 将文件集合写入磁盘，内容未变的文件不会被重写，并删除该生成器上次生成而本次不再生成的文件
+// DryRun时不修改磁盘，只输出变化的摘要或差异
+func (opts *Options) commitFiles() error {
+	if opts.files == nil {
+		return nil
+	}
+	files, generator := opts.files, opts.generator
+	opts.files, opts.generator = nil, ""
+
+	var manifest Manifest
+	var stale, generated []string
+	if generator != "" {
+		var err error
+		manifest, err = opts.loadManifest()
+		if err != nil {
+			return err
+		}
+		stale, generated = opts.staleFiles(manifest, generator, files)
+	}
+
+	if !opts.DryRun {
+		counts := make(map[FileStatus]int)
+		for _, path := range files.Paths() {
+			data, _ := files.Get(path)
+			err := os.MkdirAll(filepath.Dir(path), 0777)
+			if err != nil {
+				return err
+			}
+			status, _, err := files.Status(path)
+			if err != nil {
+				return err
+			}
+			if status != FileUnchanged {
+				_, err = util.WriteFileAtomic(path, data, 0666)
+				if err != nil {
+					return err
+				}
+			}
+			counts[status]++
+			opts.debugf("%s %s", status, opts.rel(path))
+		}
+		for _, path := range stale {
+			err := os.Remove(path)
+			if err != nil {
+				return err
+			}
+			counts[FileRemoved]++
+			opts.logf("删除 %s", opts.rel(path))
+		}
+		if generator != "" {
+			manifest[generator] = generated
+			err := opts.saveManifest(manifest)
+			if err != nil {
+				return err
+			}
+		}
+		opts.debugf("共%d个文件：%d个新增，%d个修改，%d个未变，%d个删除", len(files.Paths())+len(stale), counts[FileAdded], counts[FileModified], counts[FileUnchanged], counts[FileRemoved])
+		return nil
+	}
+
+	counts := make(map[FileStatus]int)
+	report := func(path string, status FileStatus, old, data []byte) error {
+		counts[status]++
+		rel := opts.rel(path)
+		if !opts.Diff {
+			if status == FileUnchanged {
+				opts.debugf("%s %s", status, rel)
+			} else {
+				opts.logf("%s %s", status, rel)
+			}
+			return nil
+		} else if status == FileUnchanged {
+			return nil
+		}
+		if !utf8.Valid(old) || !utf8.Valid(data) {
+			opts.logf("二进制文件 a/%s 和 b/%s 不同", rel, rel)
+			return nil
+		}
+		fromName, toName := "a/"+rel, "b/"+rel
+		switch status {
+		case FileAdded:
+			fromName = "/dev/null"
+		case FileRemoved:
+			toName = "/dev/null"
+		}
+		_, err := io.WriteString(opts.out(), util.UnifiedDiff(fromName, toName, string(old), string(data)))
+		return err
+	}
+	for _, path := range files.Paths() {
+		status, old, err := files.Status(path)
+		if err != nil {
+			return err
+		}
+		data, _ := files.Get(path)
+		err = report(path, status, old, data)
+		if err != nil {
+			return err
+		}
+	}
+	for _, path := range stale {
+		old, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		err = report(path, FileRemoved, old, nil)
+		if err != nil {
+			return err
+		}
+	}
+	opts.logf("共%d个文件：%d个新增，%d个修改，%d个未变，%d个删除", len(files.Paths())+len(stale), counts[FileAdded], counts[FileModified], counts[FileUnchanged], counts[FileRemoved])
+	return nil
+}
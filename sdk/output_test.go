@@ -0,0 +1,63 @@
+package sdk
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kkkunny/TEW-hoi4/config"
+)
+
+func TestCommitFiles(t *testing.T) {
+	statePath := config.StatePath
+	t.Cleanup(func() { config.StatePath = statePath })
+	config.StatePath = t.TempDir()
+
+	var out bytes.Buffer
+	opts := Options{ModPath: t.TempDir(), Out: &out, Verbose: true}
+	a, b := filepath.Join(opts.ModPath, "a.txt"), filepath.Join(opts.ModPath, "dir", "b.txt")
+	commit := func(files map[string]string) string {
+		t.Helper()
+		out.Reset()
+		opts.beginFiles("test")
+		for path, data := range files {
+			if err := opts.writeFile(path, []byte(data), false); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := opts.commitFiles(); err != nil {
+			t.Fatal(err)
+		}
+		return out.String()
+	}
+
+	if res := commit(map[string]string{a: "a", b: "b"}); !strings.Contains(res, "共2个文件：2个新增，0个修改，0个未变，0个删除") {
+		t.Fatalf("unexpected output:\n%s", res)
+	}
+	if res := commit(map[string]string{a: "a2", b: "b"}); !strings.Contains(res, "共2个文件：0个新增，1个修改，1个未变，0个删除") {
+		t.Fatalf("unexpected output:\n%s", res)
+	}
+	if res := commit(map[string]string{a: "a2"}); !strings.Contains(res, "删除 dir/b.txt") || !strings.Contains(res, "共2个文件：0个新增，0个修改，1个未变，1个删除") {
+		t.Fatalf("unexpected output:\n%s", res)
+	}
+	if _, err := os.Stat(b); !os.IsNotExist(err) {
+		t.Fatalf("expect stale file to be removed, got %v", err)
+	}
+
+	// 清单保存在StatePath下，不写入mod
+	entries, err := os.ReadDir(opts.ModPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range entries {
+		if e.Name() != "a.txt" && e.Name() != "dir" {
+			t.Errorf("unexpected file %s in mod", e.Name())
+		}
+	}
+	manifests, err := filepath.Glob(filepath.Join(config.StatePath, "manifests", "*.json"))
+	if err != nil || len(manifests) != 1 {
+		t.Fatalf("expect one manifest, got %v %v", manifests, err)
+	}
+}
@@ -0,0 +1,16 @@
+package sdk
+
+import (
+	"hash/fnv"
+	"math/rand/v2"
+)
+
+// newRand 返回由seed和keys（如国家tag）确定的随机数生成器，相同输入总是得到相同的序列
+func newRand(seed uint64, keys ...string) *rand.Rand {
+	h := fnv.New64a()
+	for _, key := range keys {
+		h.Write([]byte(key))
+		h.Write([]byte{0})
+	}
+	return rand.New(rand.NewPCG(seed, h.Sum64()))
+}
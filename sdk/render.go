@@ -0,0 +1,307 @@
+package sdk
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+	"path/filepath"
+	"slices"
+
+	"github.com/lucasb-eyer/go-colorful"
+
+	"github.com/kkkunny/TEW-hoi4/parser/common"
+	"github.com/kkkunny/TEW-hoi4/parser/history"
+	_map "github.com/kkkunny/TEW-hoi4/parser/map"
+	"github.com/kkkunny/TEW-hoi4/util"
+)
+
+// MapMode 地图的渲染模式
+type MapMode string
+
+const (
+	// PoliticalMap 按地区拥有者着色的政治地图
+	PoliticalMap MapMode = "political"
+	// CoreMap 某个国家拥有、有核心以及宣称的地区
+	CoreMap MapMode = "cores"
+	// ContinentMap 按definition.csv中的大洲着色
+	ContinentMap MapMode = "continents"
+)
+
+// StartDate 渲染地图时的日期，不晚于该日期的带日期历史都会生效
+const StartDate = "1936.1.1"
+
+// MapModes 支持的所有渲染模式
+var MapModes = []MapMode{PoliticalMap, CoreMap, ContinentMap}
+
+// 渲染地图使用的固定颜色
+var (
+	seaMapColor       = color.RGBA{R: 68, G: 107, B: 163, A: 255}
+	lakeMapColor      = color.RGBA{R: 110, G: 160, B: 210, A: 255}
+	landMapColor      = color.RGBA{R: 200, G: 200, B: 200, A: 255}
+	missingMapColor   = color.RGBA{R: 255, G: 0, B: 255, A: 255}
+	borderMapColor    = color.RGBA{A: 255}
+	stateBorderRatio  = float32(0.2)
+	coreBlendRatio    = float32(0.5)
+	claimStripePeriod = 6
+)
+
+// provinceStyle 省份在地图上的样式
+type provinceStyle struct {
+	fill color.RGBA
+	// stripe 不为空时按斜线条纹与fill交替填充
+	stripe *color.RGBA
+	// group 分组不同的省份之间绘制边界，如国家
+	group string
+	// state 所属地区，地区不同的陆地省份之间绘制较淡的边界，海洋省份为0
+	state int64
+}
+
+// mapRenderer 渲染地图所需的数据
+type mapRenderer struct {
+	opts        Options
+	provinceMap *_map.ProvinceMap
+	defs        map[int64]*_map.ProvinceDef
+	// provinceStates 陆地省份所属的地区
+	provinceStates map[int64]*history.State
+	// histories 各地区在StartDate时的拥有者、核心与宣称
+	histories map[int64]history.StateHistory
+	colors    map[string]*common.CountryColor
+	// missingColors 已提示过缺少颜色的国家
+	missingColors map[string]bool
+}
+
+// RenderMap 按provinces.bmp渲染地图并保存为png，tag只在CoreMap中使用
+// 国家颜色来自common/countries/colors.txt，地区的拥有者、核心和宣称来自history/states在StartDate时的历史
+func RenderMap(opts Options, mode MapMode, tag string, output string) error {
+	if !slices.Contains(MapModes, mode) {
+		return fmt.Errorf("unknown map mode `%s`", mode)
+	}
+	if mode == CoreMap && tag == "" {
+		return fmt.Errorf("map mode `%s` requires a country tag", mode)
+	}
+	if output == "" {
+		output = string(mode) + ".png"
+		if mode == CoreMap {
+			output = fmt.Sprintf("%s_%s.png", mode, tag)
+		}
+	}
+
+	r, err := newMapRenderer(opts)
+	if err != nil {
+		return err
+	}
+	if _, ok := r.colors[tag]; mode == CoreMap && !ok {
+		return fmt.Errorf("country `%s` has no color in colors.txt", tag)
+	}
+	styles := make(map[int64]provinceStyle, len(r.defs))
+	for id, def := range r.defs {
+		switch mode {
+		case PoliticalMap:
+			styles[id] = r.politicalStyle(def)
+		case CoreMap:
+			styles[id] = r.coreStyle(def, tag)
+		case ContinentMap:
+			styles[id] = r.continentStyle(def)
+		}
+	}
+
+	var buf bytes.Buffer
+	err = png.Encode(&buf, r.render(styles))
+	if err != nil {
+		return err
+	}
+	opts.beginFiles("")
+	err = opts.writeFile(output, buf.Bytes(), false)
+	if err != nil {
+		return err
+	}
+	err = opts.commitFiles()
+	if err != nil {
+		return err
+	}
+	if !opts.DryRun {
+		opts.logf("已生成%s", output)
+	}
+	return nil
+}
+
+func newMapRenderer(opts Options) (*mapRenderer, error) {
+	mapPath := filepath.Join(opts.ModPath, "map")
+	definition, err := _map.ParseDefinition(filepath.Join(mapPath, "definition.csv"))
+	if err != nil {
+		return nil, err
+	}
+	defs := definition.Map()
+	provinceMap, err := _map.ParseProvinceMap(filepath.Join(mapPath, "provinces.bmp"), defs)
+	if err != nil {
+		return nil, err
+	}
+	states, err := history.ParseStateDir(opts.ModPath)
+	if err != nil {
+		return nil, err
+	}
+	countryColors, err := common.ParseCountryColors(filepath.Join(opts.ModPath, "common", "countries", "colors.txt"))
+	if err != nil {
+		return nil, err
+	}
+
+	r := &mapRenderer{
+		opts:           opts,
+		provinceMap:    provinceMap,
+		defs:           defs,
+		provinceStates: make(map[int64]*history.State),
+		histories:      make(map[int64]history.StateHistory, len(states)),
+		colors:         make(map[string]*common.CountryColor, len(countryColors)),
+		missingColors:  make(map[string]bool),
+	}
+	for _, state := range states {
+		r.histories[state.ID] = state.History.At(StartDate)
+		for _, p := range state.Provinces {
+			if def, ok := defs[p]; ok && def.Type == _map.ProvinceTypeLand {
+				r.provinceStates[p] = state
+			}
+		}
+	}
+	for _, cc := range countryColors {
+		r.colors[cc.Country] = cc
+	}
+	width, height := provinceMap.Size()
+	opts.debugf("地图大小%dx%d，共%d个省份、%d个地区、%d个国家颜色", width, height, len(defs), len(states), len(countryColors))
+	return r, nil
+}
+
+// countryColor 返回国家在colors.txt中的颜色，没有颜色时提示一次并使用醒目的颜色
+func (r *mapRenderer) countryColor(tag string) color.RGBA {
+	cc, ok := r.colors[tag]
+	if !ok {
+		if !r.missingColors[tag] {
+			r.missingColors[tag] = true
+			r.opts.logf("国家%s在colors.txt中没有颜色", tag)
+		}
+		return missingMapColor
+	}
+	return rgba(cc.Color)
+}
+
+// waterStyle 返回海洋和湖泊的样式，陆地省份返回false
+func waterStyle(def *_map.ProvinceDef) (provinceStyle, bool) {
+	switch def.Type {
+	case _map.ProvinceTypeSea:
+		return provinceStyle{fill: seaMapColor, group: "sea"}, true
+	case _map.ProvinceTypeLake:
+		return provinceStyle{fill: lakeMapColor, group: "lake"}, true
+	default:
+		return provinceStyle{}, false
+	}
+}
+
+// landStyle 返回陆地省份的基本样式及所属地区在StartDate时的历史，不属于任何地区的省份没有拥有者
+func (r *mapRenderer) landStyle(def *_map.ProvinceDef) (provinceStyle, *history.StateHistory) {
+	style := provinceStyle{fill: landMapColor, group: "land"}
+	state, ok := r.provinceStates[def.ID]
+	if !ok {
+		return style, nil
+	}
+	style.state = state.ID
+	h := r.histories[state.ID]
+	if h.Owner != "" {
+		style.group = "country:" + h.Owner
+	}
+	return style, &h
+}
+
+func (r *mapRenderer) politicalStyle(def *_map.ProvinceDef) provinceStyle {
+	if style, ok := waterStyle(def); ok {
+		return style
+	}
+	style, h := r.landStyle(def)
+	if h != nil && h.Owner != "" {
+		style.fill = r.countryColor(h.Owner)
+	}
+	return style
+}
+
+func (r *mapRenderer) coreStyle(def *_map.ProvinceDef, tag string) provinceStyle {
+	if style, ok := waterStyle(def); ok {
+		return style
+	}
+	style, h := r.landStyle(def)
+	if h == nil {
+		return style
+	}
+	tagColor := r.countryColor(tag)
+	switch {
+	case h.Owner == tag:
+		style.fill = tagColor
+	case slices.Contains(h.Cores, tag):
+		style.fill = rgba(util.AlphaBlendColor(tagColor, color.White, coreBlendRatio))
+	case slices.Contains(h.Claims, tag):
+		style.stripe = &tagColor
+	}
+	return style
+}
+
+func (r *mapRenderer) continentStyle(def *_map.ProvinceDef) provinceStyle {
+	if style, ok := waterStyle(def); ok {
+		return style
+	}
+	style, _ := r.landStyle(def)
+	style.group = fmt.Sprintf("continent:%d", def.Continent)
+	if def.Continent != 0 {
+		// 按黄金角分配色相，相邻编号的大洲颜色差别较大
+		hue := math.Mod(float64(def.Continent)*137.508, 360)
+		red, green, blue := colorful.Hsv(hue, 0.45, 0.85).Clamped().RGB255()
+		style.fill = color.RGBA{R: red, G: green, B: blue, A: 255}
+	}
+	return style
+}
+
+// render 按省份样式绘制地图，分组不同的省份之间绘制边界，同一分组内不同地区之间绘制较淡的边界
+func (r *mapRenderer) render(styles map[int64]provinceStyle) *image.RGBA {
+	width, height := r.provinceMap.Size()
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	idAt := func(x, y int) int64 {
+		id, _ := r.provinceMap.At(x, y)
+		return id
+	}
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			style := styles[idAt(x, y)]
+			clr := style.fill
+			if style.stripe != nil && (x+y)%claimStripePeriod < claimStripePeriod/2 {
+				clr = *style.stripe
+			}
+
+			// 只与右侧和下方的像素比较，边界绘制在靠左上的一侧
+			var border, stateBorder bool
+			for _, p := range []image.Point{{X: x + 1, Y: y}, {X: x, Y: y + 1}} {
+				id, ok := r.provinceMap.At(p.X, p.Y)
+				if !ok {
+					continue
+				}
+				other := styles[id]
+				if other.group != style.group {
+					border = true
+				} else if other.state != style.state {
+					stateBorder = true
+				}
+			}
+			switch {
+			case border:
+				clr = borderMapColor
+			case stateBorder:
+				clr = rgba(util.AlphaBlendColor(clr, borderMapColor, stateBorderRatio))
+			}
+			img.SetRGBA(x, y, clr)
+		}
+	}
+	return img
+}
+
+func rgba(c color.Color) color.RGBA {
+	red, green, blue := util.GetRGB(c)
+	return color.RGBA{R: red, G: green, B: blue, A: 255}
+}
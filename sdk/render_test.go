@@ -0,0 +1,108 @@
+package sdk
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kkkunny/TEW-hoi4/internal/testutil"
+	"github.com/kkkunny/TEW-hoi4/util"
+)
+
+// renderTestMod 复制测试mod中渲染地图需要的文件，并给莱茵兰加上带日期的核心和宣称
+func renderTestMod(t *testing.T) string {
+	t.Helper()
+	modPath := t.TempDir()
+	for _, name := range []string{
+		"map/definition.csv",
+		"map/provinces.bmp",
+		"common/countries/colors.txt",
+		"history/states/1-Corsica.txt",
+		"history/states/2-Rhineland.txt",
+	} {
+		to := filepath.Join(modPath, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(to), 0777); err != nil {
+			t.Fatal(err)
+		}
+		if err := util.CopyFile(filepath.Join(testutil.ModPath(), filepath.FromSlash(name)), to); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	path := filepath.Join(modPath, "history", "states", "2-Rhineland.txt")
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 开局当天生效的核心应当绘制，开局之后的宣称不应绘制
+	dated := "\t\t1936.1.1 = {\n\t\t\tadd_core_of = FRA\n\t\t}\n\t\t1939.1.1 = {\n\t\t\tadd_claim_by = ITA\n\t\t}\n\t\t1936.3.7 = {"
+	src := strings.Replace(string(data), "\t\t1936.3.7 = {", dated, 1)
+	if src == string(data) {
+		t.Fatal("unexpected Rhineland history")
+	}
+	if err = os.WriteFile(path, []byte(src), 0666); err != nil {
+		t.Fatal(err)
+	}
+	return modPath
+}
+
+func TestRenderMap(t *testing.T) {
+	var (
+		fra     = color.RGBA{R: 57, G: 160, B: 101, A: 255}
+		ger     = color.RGBA{R: 80, G: 80, B: 80, A: 255}
+		fraCore = rgba(util.AlphaBlendColor(fra, color.White, coreBlendRatio))
+		europe  = color.RGBA{R: 119, G: 217, B: 148, A: 255}
+	)
+	// (0,0)为科西嘉的3838，(0,3)为莱茵兰，(5,0)为海洋，(3,0)为陆地与海洋之间的边界
+	tests := []struct {
+		mode   MapMode
+		tag    string
+		pixels map[image.Point]color.RGBA
+	}{
+		{mode: PoliticalMap, pixels: map[image.Point]color.RGBA{
+			{X: 0, Y: 0}: fra, {X: 0, Y: 3}: ger, {X: 5, Y: 0}: seaMapColor, {X: 3, Y: 0}: borderMapColor,
+		}},
+		{mode: CoreMap, tag: "FRA", pixels: map[image.Point]color.RGBA{
+			{X: 0, Y: 0}: fra, {X: 0, Y: 3}: fraCore, {X: 5, Y: 0}: seaMapColor,
+		}},
+		{mode: CoreMap, tag: "ITA", pixels: map[image.Point]color.RGBA{
+			{X: 0, Y: 3}: landMapColor,
+		}},
+		{mode: ContinentMap, pixels: map[image.Point]color.RGBA{
+			{X: 0, Y: 0}: europe, {X: 0, Y: 3}: europe, {X: 5, Y: 0}: seaMapColor,
+		}},
+	}
+
+	modPath := renderTestMod(t)
+	for _, tt := range tests {
+		t.Run(string(tt.mode)+tt.tag, func(t *testing.T) {
+			output := filepath.Join(t.TempDir(), "map.png")
+			err := RenderMap(Options{ModPath: modPath, Out: &bytes.Buffer{}}, tt.mode, tt.tag, output)
+			if err != nil {
+				t.Fatal(err)
+			}
+			f, err := os.Open(output)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+			img, err := png.Decode(f)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if size := img.Bounds().Size(); size != (image.Point{X: 6, Y: 4}) {
+				t.Fatalf("unexpected image size %v", size)
+			}
+			for p, want := range tt.pixels {
+				if got := color.RGBAModel.Convert(img.At(p.X, p.Y)); got != want {
+					t.Errorf("pixel %v = %v, want %v", p, got, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,221 @@
+package sdk
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	stlbasic "github.com/kkkunny/stl/basic"
+	"github.com/kkkunny/stl/container/hashset"
+	stlslices "github.com/kkkunny/stl/container/slices"
+	"gopkg.in/yaml.v3"
+
+	"github.com/kkkunny/TEW-hoi4/parser/history"
+	_map "github.com/kkkunny/TEW-hoi4/parser/map"
+)
+
+// StateRules 地区批量修改规则文件
+type StateRules struct {
+	Rules []*StateRule `yaml:"rules"`
+}
+
+// StateRule 一条规则，对所有满足Select的地区依次执行Actions
+type StateRule struct {
+	Name    string        `yaml:"name"`
+	Select  StateSelector `yaml:"select"`
+	Actions StateActions  `yaml:"actions"`
+}
+
+// StateSelector 地区筛选条件，不同条件之间为且，同一条件的多个值之间为或
+type StateSelector struct {
+	Owners     []string `yaml:"owners"`
+	Cores      []string `yaml:"cores"`
+	Claims     []string `yaml:"claims"`
+	Continents []int64  `yaml:"continents"`
+	// IDs 地区ID或ID范围，如 "100"、"100-200"
+	IDs       []string `yaml:"ids"`
+	Provinces []int64  `yaml:"provinces"`
+}
+
+// StateActions 对地区的修改
+type StateActions struct {
+	AddCores     []string `yaml:"add_cores"`
+	RemoveCores  []string `yaml:"remove_cores"`
+	AddClaims    []string `yaml:"add_claims"`
+	RemoveClaims []string `yaml:"remove_claims"`
+	Owner        *string  `yaml:"owner"`
+	Category     *string  `yaml:"category"`
+	Manpower     *int64   `yaml:"manpower"`
+	// Buildings 增加的建筑等级
+	Buildings map[string]int64 `yaml:"buildings"`
+}
+
+func ParseStateRules(path string) (*StateRules, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var rules StateRules
+	err = yaml.Unmarshal(data, &rules)
+	if err != nil {
+		return nil, err
+	}
+	for i, rule := range rules.Rules {
+		for _, id := range rule.Select.IDs {
+			if _, _, err = parseIDRange(id); err != nil {
+				return nil, fmt.Errorf("rule %d: %s", i+1, err.Error())
+			}
+		}
+	}
+	return &rules, nil
+}
+
+func parseIDRange(s string) (int64, int64, error) {
+	from, to, isRange := strings.Cut(s, "-")
+	begin, err := strconv.ParseInt(strings.TrimSpace(from), 10, 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid state id `%s`", s)
+	}
+	if !isRange {
+		return begin, begin, nil
+	}
+	end, err := strconv.ParseInt(strings.TrimSpace(to), 10, 64)
+	if err != nil || end < begin {
+		return 0, 0, fmt.Errorf("invalid state id range `%s`", s)
+	}
+	return begin, end, nil
+}
+
+func (s *StateSelector) usesContinents() bool {
+	return len(s.Continents) != 0
+}
+
+// Match 判断地区是否满足条件，provinceDefs只在按大洲筛选时使用
+func (s *StateSelector) Match(state *history.State, provinceDefs map[int64]*_map.ProvinceDef) bool {
+	if len(s.Owners) != 0 && !stlslices.Contain(s.Owners, state.History.Owner) {
+		return false
+	}
+	if len(s.Cores) != 0 && !stlslices.ContainAny(state.History.Cores, s.Cores...) {
+		return false
+	}
+	if len(s.Claims) != 0 && !stlslices.ContainAny(state.History.Claims, s.Claims...) {
+		return false
+	}
+	if len(s.Provinces) != 0 && !stlslices.ContainAny(state.Provinces, s.Provinces...) {
+		return false
+	}
+	if len(s.IDs) != 0 && !stlslices.Any(s.IDs, func(_ int, id string) bool {
+		begin, end, _ := parseIDRange(id)
+		return state.ID >= begin && state.ID <= end
+	}) {
+		return false
+	}
+	if len(s.Continents) != 0 && !stlslices.Any(state.Provinces, func(_ int, province int64) bool {
+		def, ok := provinceDefs[province]
+		return ok && stlslices.Contain(s.Continents, def.Continent)
+	}) {
+		return false
+	}
+	return true
+}
+
+func addTags(tags []string, adds ...string) []string {
+	for _, tag := range adds {
+		if !stlslices.Contain(tags, tag) {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+func removeTags(tags []string, removes ...string) []string {
+	removeSet := hashset.NewHashSetWith(removes...)
+	return stlslices.Filter(tags, func(_ int, tag string) bool {
+		return !removeSet.Contain(tag)
+	})
+}
+
+// Apply 修改地区
+func (a *StateActions) Apply(state *history.State) {
+	state.History.Cores = removeTags(addTags(state.History.Cores, a.AddCores...), a.RemoveCores...)
+	state.History.Claims = removeTags(addTags(state.History.Claims, a.AddClaims...), a.RemoveClaims...)
+	if a.Owner != nil {
+		state.History.Owner = *a.Owner
+	}
+	if a.Category != nil {
+		state.Category = *a.Category
+	}
+	if a.Manpower != nil {
+		state.Manpower = *a.Manpower
+	}
+	if len(a.Buildings) != 0 && state.History.CommonBuildings == nil {
+		state.History.CommonBuildings = make(map[string]int64, len(a.Buildings))
+	}
+	for name, level := range a.Buildings {
+		state.History.CommonBuildings[name] += level
+	}
+}
+
+// ApplyStateRules 按规则文件修改mod中的history/states，DryRun时只输出变化不写入文件
+func ApplyStateRules(opts Options, rulePath string) error {
+	modPath := opts.ModPath
+	rules, err := ParseStateRules(rulePath)
+	if err != nil {
+		return err
+	}
+	states, err := history.ParseStateDir(modPath)
+	if err != nil {
+		return err
+	}
+	var provinceDefs map[int64]*_map.ProvinceDef
+	if stlslices.Any(rules.Rules, func(_ int, rule *StateRule) bool { return rule.Select.usesContinents() }) {
+		definition, err := _map.ParseDefinition(filepath.Join(modPath, "map", "definition.csv"))
+		if err != nil {
+			return err
+		}
+		provinceDefs = definition.Map()
+	}
+
+	originals, err := stlslices.MapError(states, func(_ int, state *history.State) ([]byte, error) {
+		return state.Encode()
+	})
+	if err != nil {
+		return err
+	}
+	for _, rule := range rules.Rules {
+		for _, state := range states {
+			if rule.Select.Match(state, provinceDefs) {
+				rule.Actions.Apply(state)
+			}
+		}
+	}
+
+	opts.beginFiles("")
+	var changed int
+	for i, state := range states {
+		content, err := state.Encode()
+		if err != nil {
+			return fmt.Errorf("`%s` encode error: %s", opts.rel(state.Path()), err.Error())
+		}
+		if bytes.Equal(content, originals[i]) {
+			continue
+		}
+		changed++
+		err = opts.writeFile(state.Path(), content, false)
+		if err != nil {
+			return err
+		}
+		if !opts.DryRun {
+			opts.logf("修改 %s", opts.rel(state.Path()))
+		}
+	}
+	err = opts.commitFiles()
+	if err != nil {
+		return err
+	}
+	opts.logf("共%d个地区文件%s", changed, stlbasic.Ternary(opts.DryRun, "将被修改", "已修改"))
+	return nil
+}
@@ -0,0 +1,89 @@
+package sdk
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"text/template"
+
+	"github.com/kkkunny/TEW-hoi4/config"
+)
+
+// ModTemplateDir mod中用于覆盖内置模板的目录，其中与内置模板同名的文件优先使用
+const ModTemplateDir = "tew_templates"
+
+//go:embed templates/*.tmpl
+var templateFS embed.FS
+
+var templateFuncs = template.FuncMap{
+	// ifElse 按序号返回if或else_if，用于生成if/else_if链
+	"ifElse": func(i int) string {
+		if i == 0 {
+			return "if"
+		}
+		return "else_if"
+	},
+	// dict 由键值对构造map
+	"dict": func(kvs ...any) (map[string]any, error) {
+		if len(kvs)%2 != 0 {
+			return nil, errors.New("dict: odd number of arguments")
+		}
+		m := make(map[string]any, len(kvs)/2)
+		for i := 0; i < len(kvs); i += 2 {
+			k, ok := kvs[i].(string)
+			if !ok {
+				return nil, fmt.Errorf("dict: key %v is not a string", kvs[i])
+			}
+			m[k] = kvs[i+1]
+		}
+		return m, nil
+	},
+}
+
+// renderTemplate 渲染名为name的模板，依次在mod的ModTemplateDir、config.TemplatePath中查找同名文件，都没有时使用内置模板
+func (opts *Options) renderTemplate(name string, data any) ([]byte, error) {
+	text, err := opts.loadTemplate(name)
+	if err != nil {
+		return nil, err
+	}
+	tmpl, err := template.New(name).Funcs(templateFuncs).Option("missingkey=error").Parse(string(text))
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	err = tmpl.Execute(&buf, data)
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (opts *Options) loadTemplate(name string) ([]byte, error) {
+	dirs := []string{filepath.Join(opts.ModPath, ModTemplateDir)}
+	if config.TemplatePath != "" {
+		dirs = append(dirs, config.TemplatePath)
+	}
+	for _, dir := range dirs {
+		path := filepath.Join(dir, name)
+		data, err := os.ReadFile(path)
+		if err == nil {
+			opts.debugf("使用模板 %s", path)
+			return data, nil
+		} else if !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+	}
+	return templateFS.ReadFile("templates/" + name)
+}
+
+// writeTemplate 渲染模板并写入mod中的文件
+func (opts *Options) writeTemplate(path, name string, data any, withBOM bool) error {
+	content, err := opts.renderTemplate(name, data)
+	if err != nil {
+		return fmt.Errorf("render template `%s` error: %s", name, err.Error())
+	}
+	return opts.writeFile(path, content, withBOM)
+}
@@ -0,0 +1,49 @@
+package sdk
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kkkunny/TEW-hoi4/config"
+)
+
+func TestRenderTemplateOverride(t *testing.T) {
+	templatePath := config.TemplatePath
+	t.Cleanup(func() { config.TemplatePath = templatePath })
+	config.TemplatePath = t.TempDir()
+
+	const name = "country_tga.gfx.tmpl"
+	opts := Options{ModPath: t.TempDir()}
+	render := func() string {
+		t.Helper()
+		res, err := opts.renderTemplate(name, map[string]any{"UpgradeCountries": []map[string]string{{"ID": "FRA"}}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(res)
+	}
+	write := func(dir, text string) {
+		t.Helper()
+		if err := os.MkdirAll(dir, 0777); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(text), 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if res := render(); !strings.Contains(res, "GFX_idea_country_tag_FRA") {
+		t.Fatalf("expect embedded template, got:\n%s", res)
+	}
+	write(config.TemplatePath, "config {{range .UpgradeCountries}}{{.ID}}{{end}}")
+	if res := render(); res != "config FRA" {
+		t.Fatalf("expect template from TemplatePath, got:\n%s", res)
+	}
+	// mod中的模板优先于TemplatePath
+	write(filepath.Join(opts.ModPath, ModTemplateDir), "mod {{range .UpgradeCountries}}{{.ID}}{{end}}")
+	if res := render(); res != "mod FRA" {
+		t.Fatalf("expect template from the mod, got:\n%s", res)
+	}
+}
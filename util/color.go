@@ -13,7 +13,9 @@ func NewColorByMode(mode string, v1, v2, v3 float64) (color.Color, error) {
 	case "rgb", "":
 		return NewRGB(uint8(v1), uint8(v2), uint8(v3)), nil
 	case "hsv":
-		return colorful.Hsv(v1, v2, v3), nil
+		// hoi4中hsv的三个分量都在0~1之间
+		r, g, b := colorful.Hsv(v1*360, v2, v3).Clamped().RGB255()
+		return NewRGB(r, g, b), nil
 	default:
 		return nil, fmt.Errorf("unknown color type `%s`", mode)
 	}
@@ -25,7 +27,7 @@ func NewRGB(r, g, b uint8) color.Color {
 
 func GetRGBA(c color.Color) (uint8, uint8, uint8, uint8) {
 	r, g, b, a := c.RGBA()
-	return uint8(r & 0xFF), uint8(g & 0xFF), uint8(b & 0xFF), uint8(a & 0xFF)
+	return uint8(r >> 8), uint8(g >> 8), uint8(b >> 8), uint8(a >> 8)
 }
 
 func GetRGB(c color.Color) (uint8, uint8, uint8) {
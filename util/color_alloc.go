@@ -0,0 +1,114 @@
+package util
+
+import (
+	"image/color"
+	"math"
+	"math/rand/v2"
+
+	"github.com/lucasb-eyer/go-colorful"
+)
+
+// ColorAllocator 按CIEDE2000感知距离分配颜色，保证新颜色与已有颜色足够不同
+type ColorAllocator struct {
+	// MinDistance 与所有已有颜色的最小距离
+	MinDistance float64
+	// NeighborDistance 与相邻颜色的最小距离，通常大于MinDistance
+	NeighborDistance float64
+	// Attempts 每次分配尝试的候选颜色数量
+	Attempts int
+	// Candidate 生成候选颜色，为nil时使用RandomCountryColor
+	Candidate func(r *rand.Rand) color.Color
+
+	colors []colorful.Color
+}
+
+// NewColorAllocator 新建颜色分配器
+func NewColorAllocator(minDistance, neighborDistance float64) *ColorAllocator {
+	return &ColorAllocator{
+		MinDistance:      minDistance,
+		NeighborDistance: neighborDistance,
+		Attempts:         200,
+	}
+}
+
+// Add 登记已被使用的颜色
+func (a *ColorAllocator) Add(c color.Color) {
+	cc, _ := colorful.MakeColor(c)
+	a.colors = append(a.colors, roundColor(cc))
+}
+
+// Remove 取消登记颜色
+func (a *ColorAllocator) Remove(c color.Color) {
+	cc, _ := colorful.MakeColor(c)
+	cc = roundColor(cc)
+	for i, exist := range a.colors {
+		if exist.AlmostEqualRgb(cc) {
+			a.colors = append(a.colors[:i], a.colors[i+1:]...)
+			return
+		}
+	}
+}
+
+// Allocate 从若干候选中选出离已有颜色和neighbors最远的颜色并登记
+// 返回的ok表示是否满足MinDistance和NeighborDistance，不满足时仍返回找到的最好的颜色
+func (a *ColorAllocator) Allocate(r *rand.Rand, neighbors []color.Color) (c color.Color, ok bool) {
+	neighborColors := make([]colorful.Color, len(neighbors))
+	for i, n := range neighbors {
+		neighborColors[i], _ = colorful.MakeColor(n)
+	}
+	candidate := a.Candidate
+	if candidate == nil {
+		candidate = RandomCountryColor
+	}
+
+	var best colorful.Color
+	bestScore := math.Inf(-1)
+	for i := 0; i < max(a.Attempts, 1); i++ {
+		cc, _ := colorful.MakeColor(candidate(r))
+		cc = roundColor(cc)
+		score := a.score(cc, neighborColors)
+		if score > bestScore {
+			best, bestScore = cc, score
+		}
+		if score >= 1 && i >= a.Attempts/4 {
+			break
+		}
+	}
+	a.colors = append(a.colors, best)
+	red, green, blue := best.RGB255()
+	return NewRGB(red, green, blue), bestScore >= 1
+}
+
+// score 以与阈值的比值衡量候选颜色，取所有颜色中最差的一个，不小于1时满足要求
+func (a *ColorAllocator) score(c colorful.Color, neighbors []colorful.Color) float64 {
+	score := math.Inf(1)
+	if a.MinDistance > 0 {
+		for _, exist := range a.colors {
+			score = min(score, c.DistanceCIEDE2000(exist)/a.MinDistance)
+		}
+	}
+	if a.NeighborDistance > 0 {
+		for _, n := range neighbors {
+			score = min(score, c.DistanceCIEDE2000(n)/a.NeighborDistance)
+		}
+	}
+	return score
+}
+
+// roundColor 将颜色转换为8位rgb能表示的颜色，使距离按最终写入的颜色计算
+func roundColor(c colorful.Color) colorful.Color {
+	r, g, b := c.Clamped().RGB255()
+	return colorful.Color{R: float64(r) / 255, G: float64(g) / 255, B: float64(b) / 255}
+}
+
+// ColorDistance 两个颜色的CIEDE2000距离
+func ColorDistance(c1, c2 color.Color) float64 {
+	cc1, _ := colorful.MakeColor(c1)
+	cc2, _ := colorful.MakeColor(c2)
+	return cc1.DistanceCIEDE2000(cc2)
+}
+
+// RandomCountryColor 随机生成适合作为国家颜色的颜色
+func RandomCountryColor(r *rand.Rand) color.Color {
+	return colorful.Hsv(float64(r.IntN(361)), float64(r.IntN(71))/100, float64(50+r.IntN(51))/100)
+}
@@ -0,0 +1,71 @@
+package util
+
+import (
+	"image/color"
+	"math/rand/v2"
+	"testing"
+)
+
+// sequenceCandidate 依次返回colors中的颜色
+func sequenceCandidate(colors ...color.Color) func(r *rand.Rand) color.Color {
+	var i int
+	return func(_ *rand.Rand) color.Color {
+		c := colors[i%len(colors)]
+		i++
+		return c
+	}
+}
+
+func TestColorAllocator(t *testing.T) {
+	red, darkRed, blue := NewRGB(255, 0, 0), NewRGB(250, 0, 0), NewRGB(0, 0, 255)
+	tests := []struct {
+		name       string
+		exist      []color.Color
+		neighbors  []color.Color
+		candidates []color.Color
+		want       color.Color
+		wantOK     bool
+	}{
+		{name: "empty", candidates: []color.Color{red}, want: red, wantOK: true},
+		{name: "far enough", exist: []color.Color{red}, candidates: []color.Color{darkRed, blue}, want: blue, wantOK: true},
+		{name: "too close", exist: []color.Color{red}, candidates: []color.Color{darkRed}, want: darkRed, wantOK: false},
+		{name: "same color", exist: []color.Color{blue}, candidates: []color.Color{blue}, want: blue, wantOK: false},
+		{name: "too close to neighbor", neighbors: []color.Color{red}, candidates: []color.Color{darkRed}, want: darkRed, wantOK: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			allocator := NewColorAllocator(0.05, 0.15)
+			allocator.Attempts = len(tt.candidates)
+			allocator.Candidate = sequenceCandidate(tt.candidates...)
+			for _, c := range tt.exist {
+				allocator.Add(c)
+			}
+			got, ok := allocator.Allocate(rand.New(rand.NewPCG(1, 2)), tt.neighbors)
+			if ok != tt.wantOK {
+				t.Fatalf("expect ok %v, got %v", tt.wantOK, ok)
+			}
+			if ColorDistance(got, tt.want) > 0.001 {
+				t.Fatalf("expect %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestColorAllocatorRegisters(t *testing.T) {
+	allocator := NewColorAllocator(0.05, 0)
+	allocator.Attempts = 1
+	allocator.Candidate = sequenceCandidate(NewRGB(0, 128, 0))
+	r := rand.New(rand.NewPCG(1, 2))
+	if _, ok := allocator.Allocate(r, nil); !ok {
+		t.Fatal("expect the first allocation to succeed")
+	}
+	// 分配的颜色已被登记，同样的颜色不再满足MinDistance
+	if _, ok := allocator.Allocate(r, nil); ok {
+		t.Fatal("expect the second allocation to fail at MinDistance")
+	}
+	allocator.Remove(NewRGB(0, 128, 0))
+	allocator.Remove(NewRGB(0, 128, 0))
+	if _, ok := allocator.Allocate(r, nil); !ok {
+		t.Fatal("expect the allocation to succeed after removing the colors")
+	}
+}
@@ -0,0 +1,219 @@
+package util
+
+import (
+	"fmt"
+	"strings"
+)
+
+type diffOp struct {
+	kind byte // ' ', '-', '+'
+	line string
+}
+
+// maxDiffEdits 二分时搜索的最大编辑距离，超过时将该段视为整体替换，避免大规模改写时耗时过长
+const maxDiffEdits = 4096
+
+// myersDiff 用线性空间的Myers算法计算两组行之间的最短编辑脚本
+func myersDiff(a, b []string) []diffOp {
+	d := &differ{a: a, b: b, ops: make([]diffOp, 0, max(len(a), len(b)))}
+	d.diff(0, len(a), 0, len(b))
+	return d.ops
+}
+
+type differ struct {
+	a, b []string
+	ops  []diffOp
+}
+
+// diff 计算a[a0:a1]与b[b0:b1]的编辑脚本，去掉相同的首尾后在中间蛇处一分为二递归
+func (d *differ) diff(a0, a1, b0, b1 int) {
+	for a0 < a1 && b0 < b1 && d.a[a0] == d.b[b0] {
+		d.ops = append(d.ops, diffOp{kind: ' ', line: d.a[a0]})
+		a0++
+		b0++
+	}
+	suffix := 0
+	for a1 > a0 && b1 > b0 && d.a[a1-1] == d.b[b1-1] {
+		a1--
+		b1--
+		suffix++
+	}
+
+	if a0 == a1 || b0 == b1 {
+		d.replace(a0, a1, b0, b1)
+	} else if x, y, ok := d.bisect(a0, a1, b0, b1); ok {
+		d.diff(a0, x, b0, y)
+		d.diff(x, a1, y, b1)
+	} else {
+		d.replace(a0, a1, b0, b1)
+	}
+
+	for i := a1; i < a1+suffix; i++ {
+		d.ops = append(d.ops, diffOp{kind: ' ', line: d.a[i]})
+	}
+}
+
+// replace 删除a[a0:a1]并插入b[b0:b1]
+func (d *differ) replace(a0, a1, b0, b1 int) {
+	for _, line := range d.a[a0:a1] {
+		d.ops = append(d.ops, diffOp{kind: '-', line: line})
+	}
+	for _, line := range d.b[b0:b1] {
+		d.ops = append(d.ops, diffOp{kind: '+', line: line})
+	}
+}
+
+// bisect 同时从两端搜索，返回最短编辑路径经过的中间点，编辑距离超过maxDiffEdits时返回false
+// 只保存当前一轮的最远位置，空间与两段的长度成正比
+func (d *differ) bisect(a0, a1, b0, b1 int) (x, y int, ok bool) {
+	n, m := a1-a0, b1-b0
+	maxD := (n + m + 1) / 2
+	offset := maxD
+	vf := make([]int, 2*maxD+2)
+	vb := make([]int, 2*maxD+2)
+	for i := range vf {
+		vf[i], vb[i] = -1, -1
+	}
+	vf[offset+1], vb[offset+1] = 0, 0
+	delta := n - m
+	// delta为奇数时正向路径先与反向路径重叠
+	front := delta%2 != 0
+	// 超出编辑图的对角线不再搜索
+	var kfStart, kfEnd, kbStart, kbEnd int
+	for D := 0; D < maxD && D <= maxDiffEdits; D++ {
+		for k := -D + kfStart; k <= D-kfEnd; k += 2 {
+			i := offset + k
+			var xf int
+			if k == -D || (k != D && vf[i-1] < vf[i+1]) {
+				xf = vf[i+1]
+			} else {
+				xf = vf[i-1] + 1
+			}
+			yf := xf - k
+			for xf < n && yf < m && d.a[a0+xf] == d.b[b0+yf] {
+				xf++
+				yf++
+			}
+			vf[i] = xf
+			switch {
+			case xf > n:
+				kfEnd += 2
+			case yf > m:
+				kfStart += 2
+			case front:
+				if j := offset + delta - k; j >= 0 && j < len(vb) && vb[j] != -1 && xf >= n-vb[j] {
+					return a0 + xf, b0 + yf, true
+				}
+			}
+		}
+
+		for k := -D + kbStart; k <= D-kbEnd; k += 2 {
+			i := offset + k
+			var xb int
+			if k == -D || (k != D && vb[i-1] < vb[i+1]) {
+				xb = vb[i+1]
+			} else {
+				xb = vb[i-1] + 1
+			}
+			yb := xb - k
+			for xb < n && yb < m && d.a[a1-xb-1] == d.b[b1-yb-1] {
+				xb++
+				yb++
+			}
+			vb[i] = xb
+			switch {
+			case xb > n:
+				kbEnd += 2
+			case yb > m:
+				kbStart += 2
+			case !front:
+				if j := offset + delta - k; j >= 0 && j < len(vf) && vf[j] != -1 {
+					xf := vf[j]
+					yf := offset + xf - j
+					if xf >= n-xb {
+						return a0 + xf, b0 + yf, true
+					}
+				}
+			}
+		}
+	}
+	return 0, 0, false
+}
+
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	lines := strings.SplitAfter(s, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// UnifiedDiff 生成统一格式的差异，内容相同时返回空字符串
+func UnifiedDiff(fromName, toName, from, to string) string {
+	if from == to {
+		return ""
+	}
+	const context = 3
+	ops := myersDiff(splitLines(from), splitLines(to))
+
+	var buf strings.Builder
+	fmt.Fprintf(&buf, "--- %s\n+++ %s\n", fromName, toName)
+	for i := 0; i < len(ops); {
+		if ops[i].kind == ' ' {
+			i++
+			continue
+		}
+		// 找到当前修改块的范围，相距不超过2*context行的修改合并为一块
+		begin := max(i-context, 0)
+		end := i
+		for j := i; j < len(ops); j++ {
+			if ops[j].kind != ' ' {
+				end = j + 1
+			} else if j-end >= 2*context {
+				break
+			}
+		}
+		end = min(end+context, len(ops))
+
+		var fromLine, toLine, fromCount, toCount int
+		for _, op := range ops[:begin] {
+			if op.kind != '+' {
+				fromLine++
+			}
+			if op.kind != '-' {
+				toLine++
+			}
+		}
+		for _, op := range ops[begin:end] {
+			if op.kind != '+' {
+				fromCount++
+			}
+			if op.kind != '-' {
+				toCount++
+			}
+		}
+		fmt.Fprintf(&buf, "@@ -%s +%s @@\n", hunkRange(fromLine, fromCount), hunkRange(toLine, toCount))
+		for _, op := range ops[begin:end] {
+			buf.WriteByte(op.kind)
+			buf.WriteString(op.line)
+			if !strings.HasSuffix(op.line, "\n") {
+				buf.WriteString("\n\\ No newline at end of file\n")
+			}
+		}
+		i = end
+	}
+	return buf.String()
+}
+
+func hunkRange(line, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%d,0", line)
+	}
+	if count == 1 {
+		return fmt.Sprintf("%d", line+1)
+	}
+	return fmt.Sprintf("%d,%d", line+1, count)
+}
@@ -0,0 +1,108 @@
+package util
+
+import (
+	"math/rand/v2"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// applyOps 分别还原编辑脚本的两侧，并返回修改的行数
+func applyOps(ops []diffOp) (from, to []string, edits int) {
+	for _, op := range ops {
+		if op.kind != '+' {
+			from = append(from, op.line)
+		}
+		if op.kind != '-' {
+			to = append(to, op.line)
+		}
+		if op.kind != ' ' {
+			edits++
+		}
+	}
+	return from, to, edits
+}
+
+// lcsEdits 用动态规划计算最短编辑距离
+func lcsEdits(a, b []string) int {
+	dp := make([][]int, len(a)+1)
+	for i := range dp {
+		dp[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				dp[i][j] = dp[i+1][j+1] + 1
+			} else {
+				dp[i][j] = max(dp[i+1][j], dp[i][j+1])
+			}
+		}
+	}
+	return len(a) + len(b) - 2*dp[0][0]
+}
+
+func randomLines(r *rand.Rand, n int) []string {
+	lines := make([]string, n)
+	for i := range lines {
+		lines[i] = strconv.Itoa(r.IntN(4)) + "\n"
+	}
+	return lines
+}
+
+func TestMyersDiff(t *testing.T) {
+	r := rand.New(rand.NewPCG(1, 2))
+	for i := 0; i < 500; i++ {
+		a, b := randomLines(r, r.IntN(20)), randomLines(r, r.IntN(20))
+		from, to, edits := applyOps(myersDiff(a, b))
+		if strings.Join(from, "") != strings.Join(a, "") || strings.Join(to, "") != strings.Join(b, "") {
+			t.Fatalf("diff of %q and %q does not reproduce the inputs", a, b)
+		}
+		if want := lcsEdits(a, b); edits != want {
+			t.Fatalf("diff of %q and %q has %d edits, want %d", a, b, edits, want)
+		}
+	}
+}
+
+func TestMyersDiffLarge(t *testing.T) {
+	// 完全不同的大文件超过maxDiffEdits后整体替换
+	n := 4 * maxDiffEdits
+	a, b := make([]string, n), make([]string, n)
+	for i := range a {
+		a[i] = "a" + strconv.Itoa(i) + "\n"
+		b[i] = "b" + strconv.Itoa(i) + "\n"
+	}
+	b[n/2] = a[n/2]
+	from, to, _ := applyOps(myersDiff(a, b))
+	if len(from) != n || len(to) != n {
+		t.Fatalf("diff has %d/%d lines, want %d", len(from), len(to), n)
+	}
+}
+
+func TestUnifiedDiff(t *testing.T) {
+	from := "a\nb\nc\nd\ne\nf\ng\nh\ni\nj\nl\n"
+	to := "a\nb\nc\nD\ne\nf\ng\nh\ni\nj\nl\nk"
+	want := `--- old
++++ new
+@@ -1,7 +1,7 @@
+ a
+ b
+ c
+-d
++D
+ e
+ f
+ g
+@@ -9,3 +9,4 @@
+ i
+ j
+ l
++k
+\ No newline at end of file
+`
+	if got := UnifiedDiff("old", "new", from, to); got != want {
+		t.Errorf("UnifiedDiff() =\n%s\nwant\n%s", got, want)
+	}
+	if got := UnifiedDiff("old", "new", from, from); got != "" {
+		t.Errorf("UnifiedDiff() of equal text = %q, want empty", got)
+	}
+}
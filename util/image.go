@@ -1,6 +1,7 @@
 package util
 
 import (
+	"bytes"
 	"image"
 	"os"
 
@@ -9,24 +10,34 @@ import (
 	"github.com/ftrvxmtrx/tga"
 )
 
-func ResizeAndCopyTgaImage(f, t string, w, h uint16) error {
+// ResizeTgaImage 缩放tga图片，返回编码后的tga数据
+func ResizeTgaImage(f string, w, h uint16) ([]byte, error) {
 	inputFile, err := os.Open(f)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	defer inputFile.Close()
 	fromImg, err := tga.Decode(inputFile)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	toImg := image.NewRGBA(image.Rect(0, 0, int(w), int(h)))
 	draw.CatmullRom.Scale(toImg, toImg.Bounds(), fromImg, fromImg.Bounds(), draw.Over, nil)
 
-	outputFile, err := os.Create(t)
+	var buf bytes.Buffer
+	err = tga.Encode(&buf, toImg)
 	if err != nil {
-		panic(err)
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func ResizeAndCopyTgaImage(f, t string, w, h uint16) error {
+	data, err := ResizeTgaImage(f, w, h)
+	if err != nil {
+		return err
 	}
-	defer outputFile.Close()
-	return tga.Encode(outputFile, toImg)
+	_, err = WriteFileAtomic(t, data, 0666)
+	return err
 }
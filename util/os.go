@@ -2,19 +2,64 @@ package util
 
 import (
 	"bytes"
+	"errors"
 	"os"
+	"path/filepath"
 )
 
+// BOM utf-8的字节顺序标记
+var BOM = []byte{0xEF, 0xBB, 0xBF}
+
+// WithBOM 在数据前加上BOM
+func WithBOM(data []byte) []byte {
+	return append(append(make([]byte, 0, len(BOM)+len(data)), BOM...), data...)
+}
+
 // WriteFileWithBOM 以utf-8编码格式写入文件
 func WriteFileWithBOM(fp string, data []byte) error {
-	var dataBuf bytes.Buffer
-	_, err := dataBuf.Write([]byte{0xEF, 0xBB, 0xBF})
+	_, err := WriteFileAtomic(fp, WithBOM(data), 0644)
+	return err
+}
+
+// WriteFileAtomic 先写入同目录下的临时文件再重命名，避免中途失败留下写了一半的文件
+// 内容与已有文件相同时不写入，written为假
+func WriteFileAtomic(fp string, data []byte, perm os.FileMode) (written bool, err error) {
+	old, err := os.ReadFile(fp)
+	if err == nil && bytes.Equal(old, data) {
+		return false, nil
+	} else if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return false, err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(fp), "."+filepath.Base(fp)+".*.tmp")
+	if err != nil {
+		return false, err
+	}
+	defer func() {
+		if err != nil {
+			_ = os.Remove(tmp.Name())
+		}
+	}()
+	_, err = tmp.Write(data)
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return false, err
+	}
+	if info, statErr := os.Stat(fp); statErr == nil {
+		perm = info.Mode().Perm()
+	}
+	err = os.Chmod(tmp.Name(), perm)
 	if err != nil {
-		return err
+		return false, err
 	}
-	_, err = dataBuf.Write(data)
+	err = os.Rename(tmp.Name(), fp)
 	if err != nil {
-		return err
+		return false, err
 	}
-	return os.WriteFile(fp, dataBuf.Bytes(), 0644)
+	return true, nil
 }